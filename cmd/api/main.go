@@ -23,6 +23,10 @@ import (
 // @description     API для обработки изображений
 // @host            localhost:8000
 // @BasePath        /api/
+// @securityDefinitions.apikey AdminToken
+// @in              header
+// @name            Authorization
+// @description     Bearer <admin token>
 func main() {
 	cfg := config.NewConfig()
 
@@ -60,8 +64,11 @@ func main() {
 	}
 	slog.Info("service dependencies initialized successfully")
 
+	// Очистка брошенных загрузок по частям
+	go serviceInjector.UploadService.RunSweeper(ctx)
+
 	// Роутер
-	r := routers.SetupRouter(serviceInjector)
+	r := routers.SetupRouter(cfg, serviceInjector)
 
 	// HTTP сервер
 	srv := &http.Server{
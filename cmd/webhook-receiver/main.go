@@ -0,0 +1,156 @@
+// Локальный приёмник вебхуков для ручной проверки и интеграционных тестов:
+//
+//	POST   /webhook   - принимает вебхук, проверяет подпись (если задан секрет)
+//	GET    /received  - полученные вебхуки в порядке поступления
+//	DELETE /received  - очистить список
+//
+// -fail-first N отвечает 500 на первые N запросов, чтобы проверить ретраи.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
+)
+
+type receivedWebhook struct {
+	Headers     map[string]string `json:"headers"`
+	Payload     json.RawMessage   `json:"payload"`
+	SignatureOK bool              `json:"signature_ok"`
+	ReceivedAt  time.Time         `json:"received_at"`
+}
+
+type receiver struct {
+	secret    string
+	failFirst int
+
+	mu       sync.Mutex
+	requests int
+	received []receivedWebhook
+}
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	secret := flag.String(
+		"secret",
+		os.Getenv("WEBHOOK_SECRET"),
+		"callback secret used to verify signatures",
+	)
+	failFirst := flag.Int(
+		"fail-first",
+		0,
+		"respond 500 to the first N webhooks",
+	)
+	flag.Parse()
+
+	rcv := &receiver{secret: *secret, failFirst: *failFirst}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhook", rcv.handleWebhook)
+	mux.HandleFunc("GET /received", rcv.handleList)
+	mux.HandleFunc("DELETE /received", rcv.handleReset)
+
+	srv := &http.Server{Addr: *addr, Handler: mux}
+
+	ctx, stop := signal.NotifyContext(
+		context.Background(),
+		syscall.SIGINT,
+		syscall.SIGTERM,
+	)
+	defer stop()
+
+	go func() {
+		slog.Info("webhook receiver listening", "address", *addr)
+		if err := srv.ListenAndServe(); err != nil &&
+			!errors.Is(err, http.ErrServerClosed) {
+			slog.Error("webhook receiver failed", "error", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second,
+	)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("webhook receiver shutdown failed", "error", err)
+	}
+}
+
+func (r *receiver) handleWebhook(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	signature := req.Header.Get(ut.SignatureHeader)
+	signatureOK := r.secret == "" ||
+		ut.VerifySignature(r.secret, body, signature)
+
+	r.mu.Lock()
+	r.requests++
+	fail := r.requests <= r.failFirst
+	if !fail && signatureOK {
+		r.received = append(r.received, receivedWebhook{
+			Headers: map[string]string{
+				"X-Webhook-Event":     req.Header.Get("X-Webhook-Event"),
+				"X-Webhook-Attempt":   req.Header.Get("X-Webhook-Attempt"),
+				"X-Webhook-Timestamp": req.Header.Get("X-Webhook-Timestamp"),
+				ut.SignatureHeader:    signature,
+			},
+			Payload:     json.RawMessage(body),
+			SignatureOK: signatureOK,
+			ReceivedAt:  time.Now(),
+		})
+	}
+	r.mu.Unlock()
+
+	slog.Info("webhook received",
+		"event", req.Header.Get("X-Webhook-Event"),
+		"attempt", req.Header.Get("X-Webhook-Attempt"),
+		"signature_ok", signatureOK,
+		"simulated_failure", fail,
+	)
+
+	switch {
+	case !signatureOK:
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+	case fail:
+		http.Error(w, "simulated failure", http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (r *receiver) handleList(w http.ResponseWriter, _ *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(r.received); err != nil {
+		slog.Error("failed to encode received webhooks", "error", err)
+	}
+}
+
+func (r *receiver) handleReset(w http.ResponseWriter, _ *http.Request) {
+	r.mu.Lock()
+	r.received = nil
+	r.requests = 0
+	r.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -15,9 +15,168 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/files": {
+        "/admin/dead-letters": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Первые limit сообщений с ID задачи и последней ошибкой; очередь не изменяется",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Сообщения в dead-letter очереди",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Сколько сообщений показать (по умолчанию 100, не больше 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сообщения",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeadLettersResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный limit",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/dead-letters/replay": {
             "post": {
-                "description": "Загружает изображение в S3 и создает задачу на .",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Возвращает выбранные (или все при all=true) сообщения в очередь обработки, задачи сбрасываются в pending",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Повторная обработка сообщений из dead-letter очереди",
+                "parameters": [
+                    {
+                        "description": "Сообщения для повтора",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.DeadLetterReplayRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Результат по каждому сообщению",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeadLetterReplayResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Не указаны сообщения",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/dead-letters/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Сообщение из dead-letter очереди",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сообщения",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сообщение",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeadLetter"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Сообщение не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/batches": {
+            "post": {
+                "description": "Создаёт задачу на каждое изображение с общими параметрами. Файлы передаются несколькими частями files или ZIP-архивом; файлы, которые не удалось принять, перечисляются в rejected",
                 "consumes": [
                     "multipart/form-data"
                 ],
@@ -25,27 +184,138 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "batches"
                 ],
-                "summary": "Создать задачу на обработку изображения",
+                "summary": "Пакетная загрузка изображений",
                 "parameters": [
                     {
                         "type": "file",
-                        "description": "Изображение (JPG, PNG, max 10MB)",
-                        "name": "file",
+                        "description": "Изображения или ZIP-архив (поле можно повторять)",
+                        "name": "files",
                         "in": "formData",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Эффект (primitive, pencil)",
+                        "name": "effect",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Стиль обработки",
+                        "name": "style",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество фигур (1-5000)",
+                        "name": "num_shapes",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Тип фигур",
+                        "name": "mode",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Прозрачность (0-255, 0=auto)",
+                        "name": "alpha",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фон (avg, white, black или hex)",
+                        "name": "background",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер выходного изображения (64-4096)",
+                        "name": "output_size",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Формат результата (png, jpg, webp, svg, gif; svg только для primitive)",
+                        "name": "output_format",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Качество JPEG (1-100)",
+                        "name": "quality",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Описание фигур отдельным файлом shapes.json (только primitive)",
+                        "name": "shapes_json",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Анимация построения рисунка: gif или frames (ZIP с кадрами PNG), только primitive",
+                        "name": "animation",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Число фигур между кадрами анимации (по умолчанию около 50 кадров, не больше 200)",
+                        "name": "frame_interval",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Задержка между кадрами GIF, мс (20-5000, по умолчанию 100)",
+                        "name": "frame_delay",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "URL для вебхука по завершении каждой задачи",
+                        "name": "callback_url",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Секрет для подписи вебхука",
+                        "name": "callback_secret",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON-массив вариантов результата",
+                        "name": "variants",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Не брать готовые результаты из кэша",
+                        "name": "no_cache",
+                        "in": "formData"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Task создана, файл в S3",
+                    "202": {
+                        "description": "Пакет создан",
                         "schema": {
-                            "$ref": "#/definitions/models.UploadResponse"
+                            "$ref": "#/definitions/models.BatchResponse"
                         }
                     },
                     "400": {
-                        "description": "Неверный файл",
+                        "description": "Неверные параметры или ни одного изображения",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Слишком много файлов",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -65,20 +335,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/tasks/{id}": {
+        "/batches/{id}": {
             "get": {
-                "description": "Получить текущий статус задачи по ID",
+                "description": "Сводный статус пакета, количество задач по статусам и краткая информация о каждой задаче",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tasks"
+                    "batches"
                 ],
-                "summary": "Получить статус обработки файла",
+                "summary": "Статус пакета",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID задачи",
+                        "description": "ID пакета",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -86,13 +356,60 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Задача",
+                        "description": "Пакет",
                         "schema": {
-                            "$ref": "#/definitions/models.S3FileTask"
+                            "$ref": "#/definitions/models.BatchResponse"
                         }
                     },
                     "404": {
-                        "description": "Задача не найдена",
+                        "description": "Пакет не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/batches/{id}/archive": {
+            "get": {
+                "description": "ZIP с результатами всех завершённых задач пакета и manifest.json с исходным именем и параметрами каждого файла; незавершённые задачи перечислены в skipped. Архив передаётся потоком из S3",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "batches"
+                ],
+                "summary": "Скачать результаты пакета архивом",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пакета",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ZIP-архив",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Пакет не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Нет завершённых результатов",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -102,20 +419,1461 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.Content": {
+        },
+        "/effects": {
+            "get": {
+                "description": "Зарегистрированные эффекты и схема их параметров",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "effects"
+                ],
+                "summary": "Список доступных эффектов",
+                "responses": {
+                    "200": {
+                        "description": "Эффекты",
+                        "schema": {
+                            "$ref": "#/definitions/models.EffectsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files": {
+            "post": {
+                "description": "Загружает изображение в S3 и создает задачу на .",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Создать задачу на обработку изображения",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Изображение (JPEG, PNG, GIF, WebP, BMP, TIFF; max 10MB). Формат определяется по содержимому",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "URL изображения вместо file; можно передать и JSON-телом models.UploadURLRequest",
+                        "name": "source_url",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Эффект (primitive, pencil; по умолчанию определяется стилем)",
+                        "name": "effect",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Стиль (lowpoly, sketch, impressionism, pointillism, abstract, portrait, portrait-high, portrait-medium, portrait-low, pencil, pencil-hatched)",
+                        "name": "style",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество фигур (1-5000)",
+                        "name": "num_shapes",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Тип фигур (0=все, 1=треугольники, 2=прямоугольники, 3=эллипсы, 4=круги, 5=rotatedrect, 6=beziers, 7=rotatedellipse, 8=polygon)",
+                        "name": "mode",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Прозрачность (0-255, 0=auto)",
+                        "name": "alpha",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фон (avg, white, black или hex)",
+                        "name": "background",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер выходного изображения (64-4096)",
+                        "name": "output_size",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Формат результата (png, jpg, webp, svg, gif; svg только для primitive, webp без потерь)",
+                        "name": "output_format",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Качество JPEG (1-100, по умолчанию 90)",
+                        "name": "quality",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Описание фигур отдельным файлом shapes.json (только primitive)",
+                        "name": "shapes_json",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Анимация построения рисунка: gif или frames (ZIP с кадрами PNG), только primitive",
+                        "name": "animation",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Число фигур между кадрами анимации (по умолчанию около 50 кадров, не больше 200)",
+                        "name": "frame_interval",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Задержка между кадрами GIF, мс (20-5000, по умолчанию 100)",
+                        "name": "frame_delay",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "URL для вебхука по завершении задачи",
+                        "name": "callback_url",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Секрет для подписи вебхука (HMAC-SHA256, заголовок X-Webhook-Signature)",
+                        "name": "callback_secret",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Размытие для карандашного рисунка (0.5-50)",
+                        "name": "blur_sigma",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Текстура бумаги для карандашного рисунка",
+                        "name": "paper_texture",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Штриховка тёмных областей для карандашного рисунка",
+                        "name": "hatching",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON-массив вариантов результата, до 8: [{\\",
+                        "name": "variants",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Не брать готовый результат из кэша (тот же файл с теми же параметрами), обработать заново",
+                        "name": "no_cache",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Task создана, файл в S3",
+                        "schema": {
+                            "$ref": "#/definitions/models.UploadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Повреждённое изображение (code=corrupt_image), неверные параметры или source_url",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImageErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Файл или разрешение больше допустимого (code=image_too_large)",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImageErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Неподдерживаемый формат (code=unsupported_format)",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImageErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Источник недоступен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{fileID}/tasks": {
+            "post": {
+                "description": "Обрабатывает сохранённый upload/{fileID} с другими параметрами без повторной загрузки; результат каждой задачи пишется в processed/{fileID}/{taskID}",
+                "consumes": [
+                    "application/json",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Новая задача для уже загруженного файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID загруженного файла",
+                        "name": "fileID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Параметры обработки и вебхук",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateTaskRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Задача создана",
+                        "schema": {
+                            "$ref": "#/definitions/models.S3FileTask"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}": {
+            "get": {
+                "description": "Получить текущий статус задачи по ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Получить статус обработки файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Задача",
+                        "schema": {
+                            "$ref": "#/definitions/models.S3FileTask"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Ожидающая задача будет пропущена воркером, выполняющаяся - прервана, частичный результат удалён",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Отменить задачу",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Задача отменена",
+                        "schema": {
+                            "$ref": "#/definitions/models.S3FileTask"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Задача уже завершена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/archive": {
+            "get": {
+                "description": "ZIP со всеми завершёнными результатами задачи (основным или вариантами) и manifest.json с исходным именем и параметрами каждого файла. Архив передаётся потоком из S3",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Скачать результаты задачи архивом",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ZIP-архив",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Нет завершённых результатов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/events": {
+            "get": {
+                "description": "Сразу отправляет текущее состояние задачи, затем смены статуса и прогресс до завершения",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Поток событий задачи (SSE)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "События status и progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.TaskEvent"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/retry": {
+            "post": {
+                "description": "Перезапускает задачу в статусе failed или cancelled с тем же загруженным файлом. Прошлый запуск сохраняется в history",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Повторить задачу",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новые параметры обработки",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.RetryTaskRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Задача поставлена в очередь",
+                        "schema": {
+                            "$ref": "#/definitions/models.S3FileTask"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Задача не в статусе failed или cancelled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/upload-sessions": {
+            "post": {
+                "description": "Создаёт сессию загрузки по частям (S3 multipart upload). Файл делится на total_parts частей по part_size байт, последняя может быть меньше",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upload-sessions"
+                ],
+                "summary": "Начать возобновляемую загрузку",
+                "parameters": [
+                    {
+                        "description": "Имя, тип и размер файла",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UploadSessionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Сессия создана",
+                        "schema": {
+                            "$ref": "#/definitions/models.UploadSessionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Файл больше допустимого размера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Тип файла не image/*",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/upload-sessions/{id}": {
+            "get": {
+                "description": "Принятые части и номера частей, которые ещё нужно отправить; используется для продолжения после обрыва",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upload-sessions"
+                ],
+                "summary": "Состояние загрузки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сессия",
+                        "schema": {
+                            "$ref": "#/definitions/models.UploadSessionResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия не найдена или истекла",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет сессию и уже загруженные части",
+                "tags": [
+                    "upload-sessions"
+                ],
+                "summary": "Отменить возобновляемую загрузку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Загрузка отменена"
+                    },
+                    "404": {
+                        "description": "Сессия не найдена или истекла",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/upload-sessions/{id}/complete": {
+            "post": {
+                "description": "Собирает файл из частей и создаёт задачу на обработку. Если задачу создать не удалось, файл остаётся загруженным и задачу можно создать через /uploads/{id}/finalize",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upload-sessions"
+                ],
+                "summary": "Завершить возобновляемую загрузку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Параметры обработки и вебхук",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateTaskRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Задача создана",
+                        "schema": {
+                            "$ref": "#/definitions/models.S3FileTask"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры или повреждённое изображение (code=corrupt_image)",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImageErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия не найдена или истекла",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Загружены не все части",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Разрешение больше допустимого (code=image_too_large)",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImageErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Неподдерживаемый формат (code=unsupported_format)",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImageErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/upload-sessions/{id}/parts/{number}": {
+            "put": {
+                "description": "Тело запроса - байты части. Размер должен быть равен part_size (для последней части - остатку). Повторная отправка заменяет часть",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upload-sessions"
+                ],
+                "summary": "Загрузить часть файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер части, с 1",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Часть принята",
+                        "schema": {
+                            "$ref": "#/definitions/models.UploadPart"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный номер или размер части",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия не найдена или истекла",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads": {
+            "post": {
+                "description": "Возвращает presigned PUT (по умолчанию) или POST-политику для upload/{file_id}. Размер и Content-Type зафиксированы в подписи. После загрузки нужно вызвать /uploads/{file_id}/finalize",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Ссылка для загрузки напрямую в S3",
+                "parameters": [
+                    {
+                        "description": "Имя, тип и размер файла",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PresignUploadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Параметры загрузки",
+                        "schema": {
+                            "$ref": "#/definitions/models.PresignedUpload"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Файл больше допустимого размера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Тип файла не image/*",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads/{fileID}/finalize": {
+            "post": {
+                "description": "Проверяет загруженный в S3 объект (размер, формат и разрешение по заголовку изображения) и создаёт задачу на обработку. Объект, не прошедший проверку, удаляется",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Завершить прямую загрузку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла из /uploads",
+                        "name": "fileID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Параметры обработки и вебхук",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateTaskRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Задача создана",
+                        "schema": {
+                            "$ref": "#/definitions/models.S3FileTask"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры или повреждённое изображение (code=corrupt_image)",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImageErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Файл ещё не загружен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Файл или разрешение больше допустимого (code=image_too_large)",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImageErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Неподдерживаемый формат (code=unsupported_format)",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImageErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "counts": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expired": {
+                    "description": "Expired - задачи, чьи записи уже удалены из Redis по TTL",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "rejected": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RejectedFile"
+                    }
+                },
+                "status": {
+                    "$ref": "#/definitions/models.BatchStatus"
+                },
+                "tasks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BatchTaskSummary"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.BatchStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "processing",
+                "completed",
+                "completed_with_errors",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "BatchStatusPending",
+                "BatchStatusProcessing",
+                "BatchStatusCompleted",
+                "BatchStatusPartial",
+                "BatchStatusFailed"
+            ]
+        },
+        "models.BatchTaskSummary": {
+            "type": "object",
+            "properties": {
+                "download_url": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "percent": {
+                    "type": "number"
+                },
+                "processed_key": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.TaskStatus"
+                },
+                "task_id": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Callback": {
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CallbackAttempt": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "attempt": {
+                    "type": "integer"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Content": {
+            "type": "object",
+            "properties": {
+                "content_size": {
+                    "type": "integer"
+                },
+                "content_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateTaskRequest": {
+            "type": "object",
+            "properties": {
+                "alpha": {
+                    "type": "integer"
+                },
+                "animation": {
+                    "description": "Анимация построения рисунка: gif или frames (ZIP с PNG);\nframe_interval - фигур между кадрами, frame_delay - мс между кадрами",
+                    "type": "string"
+                },
+                "background": {
+                    "type": "string"
+                },
+                "blur_sigma": {
+                    "description": "Параметры карандашного рисунка (стили pencil, pencil-hatched)",
+                    "type": "number"
+                },
+                "callback_secret": {
+                    "type": "string"
+                },
+                "callback_url": {
+                    "type": "string"
+                },
+                "effect": {
+                    "type": "string"
+                },
+                "frame_delay": {
+                    "type": "integer"
+                },
+                "frame_interval": {
+                    "type": "integer"
+                },
+                "hatching": {
+                    "type": "boolean"
+                },
+                "mode": {
+                    "type": "integer"
+                },
+                "no_cache": {
+                    "type": "boolean"
+                },
+                "num_shapes": {
+                    "type": "integer"
+                },
+                "output_format": {
+                    "description": "Формат результата: png, jpg, webp, svg, gif; quality - для jpg",
+                    "type": "string"
+                },
+                "output_size": {
+                    "type": "integer"
+                },
+                "paper_texture": {
+                    "type": "boolean"
+                },
+                "quality": {
+                    "type": "integer"
+                },
+                "shapes_json": {
+                    "description": "ShapesJSON - описание фигур файлом shapes.json рядом с результатом",
+                    "type": "boolean"
+                },
+                "style": {
+                    "type": "string"
+                },
+                "variants": {
+                    "description": "В форме variants передаётся JSON-массивом в одном поле",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.VariantSpec"
+                    }
+                }
+            }
+        },
+        "models.DeadLetter": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "body": {
+                    "type": "string"
+                },
+                "failed_at": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "task": {
+                    "$ref": "#/definitions/models.S3FileTask"
+                },
+                "task_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DeadLetterReplayRequest": {
+            "type": "object",
+            "properties": {
+                "all": {
+                    "type": "boolean"
+                },
+                "message_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.DeadLetterReplayResponse": {
+            "type": "object",
+            "properties": {
+                "replayed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DeadLetterReplayResult"
+                    }
+                }
+            }
+        },
+        "models.DeadLetterReplayResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "replayed": {
+                    "type": "boolean"
+                },
+                "task_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DeadLettersResponse": {
+            "type": "object",
+            "properties": {
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DeadLetter"
+                    }
+                },
+                "queue": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.EffectInfo": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.EffectParam"
+                    }
+                }
+            }
+        },
+        "models.EffectParam": {
+            "type": "object",
+            "properties": {
+                "default": {},
+                "description": {
+                    "type": "string"
+                },
+                "enum": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "max": {
+                    "type": "number"
+                },
+                "min": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "type": {
+                    "description": "int, number, string, bool",
+                    "type": "string"
+                }
+            }
+        },
+        "models.EffectsResponse": {
+            "type": "object",
+            "properties": {
+                "effects": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.EffectInfo"
+                    }
+                }
+            }
+        },
+        "models.ImageErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "unsupported_format, corrupt_image или image_too_large",
+                    "type": "string",
+                    "example": "image_too_large"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "format": {
+                    "type": "string",
+                    "example": "png"
+                },
+                "height": {
+                    "type": "integer"
+                },
+                "max_height": {
+                    "type": "integer"
+                },
+                "max_pixels": {
+                    "type": "integer"
+                },
+                "max_width": {
+                    "type": "integer"
+                },
+                "supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.PresignUploadRequest": {
+            "type": "object",
+            "required": [
+                "content_type",
+                "file_name"
+            ],
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "method": {
+                    "enum": [
+                        "put",
+                        "post"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.UploadMethod"
+                        }
+                    ]
+                },
+                "size": {
+                    "description": "Size - точный размер файла, обязателен для PUT",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.PresignedUpload": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "fields": {
+                    "description": "Fields - поля формы для POST, файл передаётся последним полем file",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "file_id": {
+                    "type": "string"
+                },
+                "headers": {
+                    "description": "Headers - заголовки, которые нужно отправить с PUT",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "key": {
+                    "type": "string"
+                },
+                "max_size": {
+                    "type": "integer"
+                },
+                "method": {
+                    "$ref": "#/definitions/models.UploadMethod"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ProcessingParams": {
+            "type": "object",
+            "properties": {
+                "alpha": {
+                    "type": "integer"
+                },
+                "animation": {
+                    "description": "Анимация построения рисунка: gif или frames (ZIP с PNG);\nframe_interval - фигур между кадрами, frame_delay - мс между кадрами",
+                    "type": "string"
+                },
+                "background": {
+                    "type": "string"
+                },
+                "blur_sigma": {
+                    "description": "Параметры карандашного рисунка (стили pencil, pencil-hatched)",
+                    "type": "number"
+                },
+                "effect": {
+                    "type": "string"
+                },
+                "frame_delay": {
+                    "type": "integer"
+                },
+                "frame_interval": {
+                    "type": "integer"
+                },
+                "hatching": {
+                    "type": "boolean"
+                },
+                "mode": {
+                    "type": "integer"
+                },
+                "num_shapes": {
+                    "type": "integer"
+                },
+                "output_format": {
+                    "description": "Формат результата: png, jpg, webp, svg, gif; quality - для jpg",
+                    "type": "string"
+                },
+                "output_size": {
+                    "type": "integer"
+                },
+                "paper_texture": {
+                    "type": "boolean"
+                },
+                "quality": {
+                    "type": "integer"
+                },
+                "shapes_json": {
+                    "description": "ShapesJSON - описание фигур файлом shapes.json рядом с результатом",
+                    "type": "boolean"
+                },
+                "style": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RejectedFile": {
             "type": "object",
             "properties": {
-                "content_size": {
-                    "type": "integer"
+                "error": {
+                    "type": "string"
                 },
-                "content_type": {
+                "file_name": {
                     "type": "string"
                 }
             }
         },
+        "models.RetryTaskRequest": {
+            "type": "object",
+            "properties": {
+                "params": {
+                    "$ref": "#/definitions/models.ProcessingParams"
+                }
+            }
+        },
         "models.S3FileInfo": {
             "type": "object",
             "properties": {
@@ -130,12 +1888,44 @@ const docTemplate = `{
                 },
                 "file_name": {
                     "type": "string"
+                },
+                "sha256": {
+                    "description": "SHA256 - хэш содержимого, посчитанный при загрузке (hex)",
+                    "type": "string"
+                },
+                "source_url": {
+                    "description": "SourceURL - откуда скачан исходник, если он загружен по source_url",
+                    "type": "string"
                 }
             }
         },
         "models.S3FileTask": {
             "type": "object",
             "properties": {
+                "artifacts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TaskArtifact"
+                    }
+                },
+                "attempts": {
+                    "type": "integer"
+                },
+                "batch_id": {
+                    "type": "string"
+                },
+                "cache_hit": {
+                    "type": "boolean"
+                },
+                "callback": {
+                    "$ref": "#/definitions/models.Callback"
+                },
+                "callback_attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CallbackAttempt"
+                    }
+                },
                 "completed_at": {
                     "type": "string"
                 },
@@ -151,17 +1941,134 @@ const docTemplate = `{
                 "file_info": {
                     "$ref": "#/definitions/models.S3FileInfo"
                 },
+                "history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TaskRun"
+                    }
+                },
                 "id": {
                     "type": "string"
                 },
+                "next_retry_at": {
+                    "type": "string"
+                },
+                "no_cache": {
+                    "type": "boolean"
+                },
+                "params": {
+                    "$ref": "#/definitions/models.ProcessingParams"
+                },
                 "processed_key": {
                     "type": "string"
                 },
+                "progress": {
+                    "$ref": "#/definitions/models.TaskProgress"
+                },
+                "run": {
+                    "description": "Run - номер запуска, растёт при каждом повторе задачи;\nAttempts - номер текущей попытки внутри запуска",
+                    "type": "integer"
+                },
                 "status": {
                     "$ref": "#/definitions/models.TaskStatus"
                 },
                 "updated_at": {
                     "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TaskVariant"
+                    }
+                }
+            }
+        },
+        "models.TaskArtifact": {
+            "type": "object",
+            "properties": {
+                "download_url": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "mime_type": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "shapes"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.TaskEvent": {
+            "type": "object",
+            "properties": {
+                "task": {
+                    "$ref": "#/definitions/models.S3FileTask"
+                },
+                "type": {
+                    "$ref": "#/definitions/models.TaskEventType"
+                }
+            }
+        },
+        "models.TaskEventType": {
+            "type": "string",
+            "enum": [
+                "status",
+                "progress"
+            ],
+            "x-enum-varnames": [
+                "TaskEventStatus",
+                "TaskEventProgress"
+            ]
+        },
+        "models.TaskProgress": {
+            "type": "object",
+            "properties": {
+                "done": {
+                    "type": "integer"
+                },
+                "eta_seconds": {
+                    "type": "number"
+                },
+                "percent": {
+                    "type": "number"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TaskRun": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "params": {
+                    "$ref": "#/definitions/models.ProcessingParams"
+                },
+                "run": {
+                    "type": "integer"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.TaskStatus"
                 }
             }
         },
@@ -171,15 +2078,86 @@ const docTemplate = `{
                 "pending",
                 "processing",
                 "completed",
-                "failed"
+                "failed",
+                "cancelled"
             ],
             "x-enum-varnames": [
                 "TaskStatusPending",
                 "TaskStatusProcessing",
                 "TaskStatusCompleted",
-                "TaskStatusFailed"
+                "TaskStatusFailed",
+                "TaskStatusCancelled"
+            ]
+        },
+        "models.TaskVariant": {
+            "type": "object",
+            "properties": {
+                "artifacts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TaskArtifact"
+                    }
+                },
+                "cache_hit": {
+                    "type": "boolean"
+                },
+                "download_url": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "height": {
+                    "type": "integer"
+                },
+                "mime_type": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "params": {
+                    "$ref": "#/definitions/models.ProcessingParams"
+                },
+                "processed_key": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.TaskStatus"
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.UploadMethod": {
+            "type": "string",
+            "enum": [
+                "put",
+                "post"
+            ],
+            "x-enum-varnames": [
+                "UploadMethodPut",
+                "UploadMethodPost"
             ]
         },
+        "models.UploadPart": {
+            "type": "object",
+            "properties": {
+                "etag": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
         "models.UploadResponse": {
             "type": "object",
             "properties": {
@@ -202,6 +2180,144 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "models.UploadSessionRequest": {
+            "type": "object",
+            "required": [
+                "content_type",
+                "file_name",
+                "size"
+            ],
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.UploadSessionResponse": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "missing_parts": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "part_size": {
+                    "description": "PartSize - размер каждой части, кроме последней",
+                    "type": "integer"
+                },
+                "parts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.UploadPart"
+                    }
+                },
+                "received": {
+                    "type": "integer"
+                },
+                "s3_upload_id": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "total_parts": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.VariantSpec": {
+            "type": "object",
+            "properties": {
+                "alpha": {
+                    "type": "integer"
+                },
+                "animation": {
+                    "description": "Анимация построения рисунка: gif или frames (ZIP с PNG);\nframe_interval - фигур между кадрами, frame_delay - мс между кадрами",
+                    "type": "string"
+                },
+                "background": {
+                    "type": "string"
+                },
+                "blur_sigma": {
+                    "description": "Параметры карандашного рисунка (стили pencil, pencil-hatched)",
+                    "type": "number"
+                },
+                "effect": {
+                    "type": "string"
+                },
+                "frame_delay": {
+                    "type": "integer"
+                },
+                "frame_interval": {
+                    "type": "integer"
+                },
+                "hatching": {
+                    "type": "boolean"
+                },
+                "mode": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "num_shapes": {
+                    "type": "integer"
+                },
+                "output_format": {
+                    "description": "Формат результата: png, jpg, webp, svg, gif; quality - для jpg",
+                    "type": "string"
+                },
+                "output_size": {
+                    "type": "integer"
+                },
+                "paper_texture": {
+                    "type": "boolean"
+                },
+                "quality": {
+                    "type": "integer"
+                },
+                "shapes_json": {
+                    "description": "ShapesJSON - описание фигур файлом shapes.json рядом с результатом",
+                    "type": "boolean"
+                },
+                "style": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "Bearer \u003cadmin token\u003e",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -0,0 +1,10 @@
+package config
+
+// AdminConfig - доступ к /api/admin; пустой токен отключает эти маршруты
+type AdminConfig struct {
+	Token string `yaml:"token" envconfig:"admin_token"`
+}
+
+func NewAdminConfig() *AdminConfig {
+	return &AdminConfig{}
+}
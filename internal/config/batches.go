@@ -0,0 +1,13 @@
+package config
+
+type BatchConfig struct {
+	MaxFiles          int `yaml:"max_files" envconfig:"batch_max_files"`
+	UploadConcurrency int `yaml:"upload_concurrency" envconfig:"batch_upload_concurrency"`
+}
+
+func NewBatchConfig() *BatchConfig {
+	return &BatchConfig{
+		MaxFiles:          500,
+		UploadConcurrency: 4,
+	}
+}
@@ -0,0 +1,14 @@
+package config
+
+// CacheConfig - кэш результатов по (SHA-256 исходника, эффект, параметры)
+type CacheConfig struct {
+	Enabled bool `yaml:"enabled" envconfig:"result_cache_enabled"`
+	TTLSec  int  `yaml:"ttl_sec" envconfig:"result_cache_ttl_sec"`
+}
+
+func NewCacheConfig() *CacheConfig {
+	return &CacheConfig{
+		Enabled: true,
+		TTLSec:  7 * 24 * 3600, // неделя
+	}
+}
@@ -10,22 +10,36 @@ import (
 )
 
 type Config struct {
-	InstanceConfig  InstanceConfig  `yaml:"instance"`
-	S3StorageConfig S3StorageConfig `yaml:"s3storage"`
-	RedisConfig     RedisConfig     `yaml:"redis"`
-	RabbitMQConfig  RabbitMQConfig  `yaml:"rabbitMQ"`
-	LogConfig       LogConfig       `yaml:"logging"`
-	ConfigPath      string          `envconfig:"config_path"`
+	InstanceConfig   InstanceConfig   `yaml:"instance"`
+	S3StorageConfig  S3StorageConfig  `yaml:"s3storage"`
+	RedisConfig      RedisConfig      `yaml:"redis"`
+	RabbitMQConfig   RabbitMQConfig   `yaml:"rabbitMQ"`
+	ProcessingConfig ProcessingConfig `yaml:"processing"`
+	WebhookConfig    WebhookConfig    `yaml:"webhooks"`
+	AdminConfig      AdminConfig      `yaml:"admin"`
+	BatchConfig      BatchConfig      `yaml:"batches"`
+	FetchConfig      FetchConfig      `yaml:"fetch"`
+	CacheConfig      CacheConfig      `yaml:"result_cache"`
+	ImageConfig      ImageConfig      `yaml:"images"`
+	LogConfig        LogConfig        `yaml:"logging"`
+	ConfigPath       string           `envconfig:"config_path"`
 }
 
 func NewConfig() *Config {
 	cfg := &Config{
-		InstanceConfig:  *NewInstanceConfig(),
-		S3StorageConfig: *NewS3StorageConfig(),
-		RedisConfig:     *NewRedisConfig(),
-		RabbitMQConfig:  *NewRabbitMQConfig(),
-		LogConfig:       *NewLogConfig(),
-		ConfigPath:      "config.yaml",
+		InstanceConfig:   *NewInstanceConfig(),
+		S3StorageConfig:  *NewS3StorageConfig(),
+		RedisConfig:      *NewRedisConfig(),
+		RabbitMQConfig:   *NewRabbitMQConfig(),
+		ProcessingConfig: *NewProcessingConfig(),
+		WebhookConfig:    *NewWebhookConfig(),
+		AdminConfig:      *NewAdminConfig(),
+		BatchConfig:      *NewBatchConfig(),
+		FetchConfig:      *NewFetchConfig(),
+		CacheConfig:      *NewCacheConfig(),
+		ImageConfig:      *NewImageConfig(),
+		LogConfig:        *NewLogConfig(),
+		ConfigPath:       "config.yaml",
 	}
 
 	slog.Info(
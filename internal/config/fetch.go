@@ -0,0 +1,18 @@
+package config
+
+// FetchConfig - загрузка исходников по source_url
+type FetchConfig struct {
+	TimeoutSec   int `yaml:"timeout_sec" envconfig:"fetch_timeout_sec"`
+	MaxRedirects int `yaml:"max_redirects" envconfig:"fetch_max_redirects"`
+	// 0 - тот же лимит, что и для загрузки файлов (s3 max_upload_size)
+	MaxSize int64 `yaml:"max_size" envconfig:"fetch_max_size"`
+	// разрешить адреса локальной сети (только для разработки)
+	AllowPrivate bool `yaml:"allow_private" envconfig:"fetch_allow_private"`
+}
+
+func NewFetchConfig() *FetchConfig {
+	return &FetchConfig{
+		TimeoutSec:   30,
+		MaxRedirects: 3,
+	}
+}
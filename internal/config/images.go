@@ -0,0 +1,19 @@
+package config
+
+// ImageConfig - ограничения на загружаемые изображения, проверяются по
+// заголовку до записи в S3
+type ImageConfig struct {
+	MaxWidth  int `yaml:"max_width" envconfig:"image_max_width"`
+	MaxHeight int `yaml:"max_height" envconfig:"image_max_height"`
+	// защита от decompression bomb: декодированное изображение занимает
+	// 4 байта на пиксель независимо от размера файла
+	MaxPixels int64 `yaml:"max_pixels" envconfig:"image_max_pixels"`
+}
+
+func NewImageConfig() *ImageConfig {
+	return &ImageConfig{
+		MaxWidth:  16384,
+		MaxHeight: 16384,
+		MaxPixels: 50_000_000, // ~200 МБ в RGBA
+	}
+}
@@ -0,0 +1,16 @@
+package config
+
+type ProcessingConfig struct {
+	TaskTimeoutSec int `yaml:"task_timeout_sec" envconfig:"processing_task_timeout"`
+	MaxErrorLength int `yaml:"max_error_length" envconfig:"processing_max_error_length"`
+	// минимальный интервал между записями прогресса в Redis
+	ProgressIntervalMs int `yaml:"progress_interval_ms" envconfig:"processing_progress_interval_ms"`
+}
+
+func NewProcessingConfig() *ProcessingConfig {
+	return &ProcessingConfig{
+		TaskTimeoutSec:     600, // 10 минут
+		MaxErrorLength:     2048,
+		ProgressIntervalMs: 1000,
+	}
+}
@@ -10,6 +10,8 @@ type S3StorageConfig struct {
 	MaxUploadSize     int64  `yaml:"max_upload_size" envconfig:"s3_max_upload_size"`
 	ChunkUploadSize   int64  `yaml:"chunk_upload_size" envconfig:"s3_chunk_upload_size"`
 	UploadConcurrency uint16 `yaml:"upload_concurrency" envconfig:"upload_concurrency"`
+	// срок действия presigned-ссылок для прямой загрузки в S3
+	PresignExpirySec int `yaml:"presign_expiry_sec" envconfig:"s3_presign_expiry_sec"`
 }
 
 func NewS3StorageConfig() *S3StorageConfig {
@@ -23,5 +25,6 @@ func NewS3StorageConfig() *S3StorageConfig {
 		MaxUploadSize:     104857600, // 100 MB
 		ChunkUploadSize:   5242880,   // 5 MB
 		UploadConcurrency: 5,
+		PresignExpirySec:  900,
 	}
 }
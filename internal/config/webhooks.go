@@ -0,0 +1,20 @@
+package config
+
+type WebhookConfig struct {
+	MaxAttempts      int `yaml:"max_attempts" envconfig:"webhook_max_attempts"`
+	InitialBackoffMs int `yaml:"initial_backoff_ms" envconfig:"webhook_initial_backoff_ms"`
+	MaxBackoffMs     int `yaml:"max_backoff_ms" envconfig:"webhook_max_backoff_ms"`
+	TimeoutSec       int `yaml:"timeout_sec" envconfig:"webhook_timeout_sec"`
+	// разрешить адреса локальной сети, например для локального
+	// приёмника вебхуков (только для разработки)
+	AllowPrivate bool `yaml:"allow_private" envconfig:"webhook_allow_private"`
+}
+
+func NewWebhookConfig() *WebhookConfig {
+	return &WebhookConfig{
+		MaxAttempts:      5,
+		InitialBackoffMs: 1000,
+		MaxBackoffMs:     60000,
+		TimeoutSec:       10,
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/injectors"
+	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/BagRoman01/image-sketch-processor/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	DeadLetterService *services.DeadLetterService
+}
+
+func NewAdminHandler(
+	serviceInjector *injectors.ServiceInjector,
+) *AdminHandler {
+	return &AdminHandler{
+		DeadLetterService: serviceInjector.DeadLetterService,
+	}
+}
+
+// ListDeadLetters godoc
+// @Summary      Сообщения в dead-letter очереди
+// @Description  Первые limit сообщений с ID задачи и последней ошибкой; очередь не изменяется
+// @Tags         admin
+// @Produce      application/json
+// @Security     AdminToken
+// @Param        limit  query  int  false  "Сколько сообщений показать (по умолчанию 100, не больше 1000)"
+// @Success      200  {object}  models.DeadLettersResponse "Сообщения"
+// @Failure      400  {object}  map[string]string "Некорректный limit"
+// @Failure      401  {object}  map[string]string "Неверный токен"
+// @Router       /admin/dead-letters [get]
+func (h *AdminHandler) ListDeadLetters(c *gin.Context) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest,
+				gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = n
+	}
+
+	messages, err := h.DeadLetterService.List(c.Request.Context(), limit)
+	if err != nil {
+		logger.Error("failed to list dead letters", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.DeadLettersResponse{
+		Queue:    h.DeadLetterService.Queue(),
+		Messages: messages,
+	})
+}
+
+// GetDeadLetter godoc
+// @Summary      Сообщение из dead-letter очереди
+// @Tags         admin
+// @Produce      application/json
+// @Security     AdminToken
+// @Param        id  path  string  true  "ID сообщения"
+// @Success      200  {object}  models.DeadLetter "Сообщение"
+// @Failure      401  {object}  map[string]string "Неверный токен"
+// @Failure      404  {object}  map[string]string "Сообщение не найдено"
+// @Router       /admin/dead-letters/{id} [get]
+func (h *AdminHandler) GetDeadLetter(c *gin.Context) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+	messageID := c.Param("id")
+
+	message, err := h.DeadLetterService.Get(c.Request.Context(), messageID)
+	if err != nil {
+		if errors.Is(err, services.ErrDeadLetterNotFound) {
+			c.JSON(http.StatusNotFound,
+				gin.H{"error": "dead letter not found"})
+			return
+		}
+		logger.Error("failed to get dead letter",
+			"message_id", messageID,
+			"error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+// ReplayDeadLetters godoc
+// @Summary      Повторная обработка сообщений из dead-letter очереди
+// @Description  Возвращает выбранные (или все при all=true) сообщения в очередь обработки, задачи сбрасываются в pending
+// @Tags         admin
+// @Accept       application/json
+// @Produce      application/json
+// @Security     AdminToken
+// @Param        request  body  models.DeadLetterReplayRequest  true  "Сообщения для повтора"
+// @Success      200  {object}  models.DeadLetterReplayResponse "Результат по каждому сообщению"
+// @Failure      400  {object}  map[string]string "Не указаны сообщения"
+// @Failure      401  {object}  map[string]string "Неверный токен"
+// @Router       /admin/dead-letters/replay [post]
+func (h *AdminHandler) ReplayDeadLetters(c *gin.Context) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+
+	var req models.DeadLetterReplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.All && len(req.MessageIDs) == 0 {
+		c.JSON(http.StatusBadRequest,
+			gin.H{"error": "specify message_ids or set all to true"})
+		return
+	}
+
+	resp, err := h.DeadLetterService.Replay(
+		c.Request.Context(),
+		req.MessageIDs,
+		req.All,
+	)
+	if err != nil {
+		logger.Error("failed to replay dead letters", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// writeArchive - отдаёт архив потоком. Ошибка после начала ответа
+// обрывает соединение, чтобы клиент не принял усечённый ZIP за целый
+func writeArchive(
+	c *gin.Context,
+	archiveService *services.ArchiveService,
+	archive *services.Archive,
+) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="`+archive.Name+`"`)
+	c.Status(http.StatusOK)
+
+	if err := archiveService.WriteArchive(
+		c.Request.Context(),
+		c.Writer,
+		archive,
+	); err != nil {
+		logger.Error("failed to stream archive",
+			"archive", archive.Name,
+			"error", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// archiveError - ответ на ошибку подготовки архива (до начала записи)
+func archiveError(c *gin.Context, err error, notFound error, what string) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+
+	switch {
+	case errors.Is(err, notFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": what + " not found"})
+	case errors.Is(err, services.ErrArchiveEmpty):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logger.Error("failed to prepare archive", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to prepare archive",
+		})
+	}
+}
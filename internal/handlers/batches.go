@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/injectors"
+	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/BagRoman01/image-sketch-processor/internal/services"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// batchFileFields - поля формы, из которых берутся файлы пакета
+var batchFileFields = []string{"files", "file", "archive"}
+
+type BatchesHandler struct {
+	BatchService   *services.BatchService
+	ArchiveService *services.ArchiveService
+}
+
+func NewBatchesHandler(
+	serviceInjector *injectors.ServiceInjector,
+) *BatchesHandler {
+	return &BatchesHandler{
+		BatchService:   serviceInjector.BatchService,
+		ArchiveService: serviceInjector.ArchiveService,
+	}
+}
+
+// CreateBatch godoc
+// @Summary      Пакетная загрузка изображений
+// @Description  Создаёт задачу на каждое изображение с общими параметрами. Файлы передаются несколькими частями files или ZIP-архивом; файлы, которые не удалось принять, перечисляются в rejected
+// @Tags         batches
+// @Accept       multipart/form-data
+// @Produce      application/json
+// @Param        files            formData  file    true   "Изображения или ZIP-архив (поле можно повторять)"
+// @Param        effect           formData  string  false  "Эффект (primitive, pencil)"
+// @Param        style            formData  string  false  "Стиль обработки"
+// @Param        num_shapes       formData  int     false  "Количество фигур (1-5000)"
+// @Param        mode             formData  int     false  "Тип фигур"
+// @Param        alpha            formData  int     false  "Прозрачность (0-255, 0=auto)"
+// @Param        background       formData  string  false  "Фон (avg, white, black или hex)"
+// @Param        output_size      formData  int     false  "Размер выходного изображения (64-4096)"
+// @Param        output_format    formData  string  false  "Формат результата (png, jpg, webp, svg, gif; svg только для primitive)"
+// @Param        quality          formData  int     false  "Качество JPEG (1-100)"
+// @Param        shapes_json      formData  bool    false  "Описание фигур отдельным файлом shapes.json (только primitive)"
+// @Param        animation        formData  string  false  "Анимация построения рисунка: gif или frames (ZIP с кадрами PNG), только primitive"
+// @Param        frame_interval   formData  int     false  "Число фигур между кадрами анимации (по умолчанию около 50 кадров, не больше 200)"
+// @Param        frame_delay      formData  int     false  "Задержка между кадрами GIF, мс (20-5000, по умолчанию 100)"
+// @Param        callback_url     formData  string  false  "URL для вебхука по завершении каждой задачи"
+// @Param        callback_secret  formData  string  false  "Секрет для подписи вебхука"
+// @Param        variants         formData  string  false  "JSON-массив вариантов результата"
+// @Param        no_cache         formData  bool    false  "Не брать готовые результаты из кэша"
+// @Success      202  {object}  models.BatchResponse  "Пакет создан"
+// @Failure      400  {object}  map[string]string     "Неверные параметры или ни одного изображения"
+// @Failure      413  {object}  map[string]string     "Слишком много файлов"
+// @Failure      500  {object}  map[string]string     "Ошибка сервера"
+// @Router       /batches [post]
+func (h *BatchesHandler) CreateBatch(c *gin.Context) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		logger.Warn("invalid batch upload form", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "multipart form is required",
+		})
+		return
+	}
+
+	var files []*multipart.FileHeader
+	for _, field := range batchFileFields {
+		files = append(files, form.File[field]...)
+	}
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "at least one file is required",
+		})
+		return
+	}
+
+	var req models.CreateTaskRequest
+	if err := c.ShouldBind(&req); err != nil {
+		logger.Warn("invalid processing parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid processing parameters",
+		})
+		return
+	}
+
+	req.Variants, err = formVariants(c)
+	if err != nil {
+		logger.Warn("invalid variants", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "variants must be a JSON array of parameter objects",
+		})
+		return
+	}
+
+	batch, err := h.BatchService.CreateBatch(
+		c.Request.Context(),
+		files,
+		req.TaskOptions(),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, ut.ErrInvalidParams),
+			errors.Is(err, services.ErrInvalidCallback):
+			logger.Warn("rejected processing parameters", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrBatchEmpty):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":    err.Error(),
+				"rejected": batch.Rejected,
+			})
+		case errors.Is(err, services.ErrBatchTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			logger.Error("failed to create batch", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to create batch",
+			})
+		}
+		return
+	}
+
+	resp, err := h.BatchService.GetBatch(c.Request.Context(), batch.ID)
+	if err != nil {
+		logger.Error("failed to get created batch",
+			"batch_id", batch.ID,
+			"error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// GetBatch godoc
+// @Summary      Статус пакета
+// @Description  Сводный статус пакета, количество задач по статусам и краткая информация о каждой задаче
+// @Tags         batches
+// @Produce      application/json
+// @Param        id  path  string  true  "ID пакета"
+// @Success      200  {object}  models.BatchResponse  "Пакет"
+// @Failure      404  {object}  map[string]string     "Пакет не найден"
+// @Router       /batches/{id} [get]
+func (h *BatchesHandler) GetBatch(c *gin.Context) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+	batchID := c.Param("id")
+
+	resp, err := h.BatchService.GetBatch(c.Request.Context(), batchID)
+	if err != nil {
+		if errors.Is(err, services.ErrBatchNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "batch not found"})
+			return
+		}
+		logger.Error("failed to get batch", "batch_id", batchID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// DownloadBatchArchive godoc
+// @Summary      Скачать результаты пакета архивом
+// @Description  ZIP с результатами всех завершённых задач пакета и manifest.json с исходным именем и параметрами каждого файла; незавершённые задачи перечислены в skipped. Архив передаётся потоком из S3
+// @Tags         batches
+// @Produce      application/zip
+// @Param        id  path  string  true  "ID пакета"
+// @Success      200  {file}    binary             "ZIP-архив"
+// @Failure      404  {object}  map[string]string  "Пакет не найден"
+// @Failure      409  {object}  map[string]string  "Нет завершённых результатов"
+// @Router       /batches/{id}/archive [get]
+func (h *BatchesHandler) DownloadBatchArchive(c *gin.Context) {
+	batchID := c.Param("id")
+
+	archive, err := h.ArchiveService.BatchArchive(
+		c.Request.Context(),
+		batchID,
+	)
+	if err != nil {
+		archiveError(c, err, services.ErrBatchNotFound, "batch")
+		return
+	}
+
+	writeArchive(c, h.ArchiveService, archive)
+}
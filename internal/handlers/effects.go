@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/injectors"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/BagRoman01/image-sketch-processor/internal/processors"
+	"github.com/gin-gonic/gin"
+)
+
+type EffectsHandler struct {
+	Processors *processors.Registry
+}
+
+func NewEffectsHandler(
+	serviceInjector *injectors.ServiceInjector,
+) *EffectsHandler {
+	return &EffectsHandler{
+		Processors: serviceInjector.Processors,
+	}
+}
+
+// ListEffects godoc
+// @Summary      Список доступных эффектов
+// @Description  Зарегистрированные эффекты и схема их параметров
+// @Tags         effects
+// @Produce      application/json
+// @Success      200  {object}  models.EffectsResponse "Эффекты"
+// @Router       /effects [get]
+func (h *EffectsHandler) ListEffects(c *gin.Context) {
+	c.JSON(http.StatusOK, models.EffectsResponse{
+		Effects: h.Processors.List(),
+	})
+}
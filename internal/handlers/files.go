@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/BagRoman01/image-sketch-processor/internal/injectors"
 	"github.com/BagRoman01/image-sketch-processor/internal/logging"
 	"github.com/BagRoman01/image-sketch-processor/internal/models"
 	"github.com/BagRoman01/image-sketch-processor/internal/services"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 type FilesHandler struct {
@@ -26,19 +30,66 @@ func NewFilesHandler(serviceInjector *injectors.ServiceInjector) *FilesHandler {
 // @Tags         files
 // @Accept       multipart/form-data
 // @Produce      application/json
-// @Param        file  formData  file  true  "Изображение (JPG, PNG, max 10MB)"
+// @Param        file         formData  file    false  "Изображение (JPEG, PNG, GIF, WebP, BMP, TIFF; max 10MB). Формат определяется по содержимому"
+// @Param        source_url   formData  string  false  "URL изображения вместо file; можно передать и JSON-телом models.UploadURLRequest"
+// @Param        effect       formData  string  false  "Эффект (primitive, pencil; по умолчанию определяется стилем)"
+// @Param        style        formData  string  false  "Стиль (lowpoly, sketch, impressionism, pointillism, abstract, portrait, portrait-high, portrait-medium, portrait-low, pencil, pencil-hatched)"
+// @Param        num_shapes   formData  int     false  "Количество фигур (1-5000)"
+// @Param        mode         formData  int     false  "Тип фигур (0=все, 1=треугольники, 2=прямоугольники, 3=эллипсы, 4=круги, 5=rotatedrect, 6=beziers, 7=rotatedellipse, 8=polygon)"
+// @Param        alpha        formData  int     false  "Прозрачность (0-255, 0=auto)"
+// @Param        background   formData  string  false  "Фон (avg, white, black или hex)"
+// @Param        output_size  formData  int     false  "Размер выходного изображения (64-4096)"
+// @Param        output_format  formData  string  false  "Формат результата (png, jpg, webp, svg, gif; svg только для primitive, webp без потерь)"
+// @Param        quality        formData  int     false  "Качество JPEG (1-100, по умолчанию 90)"
+// @Param        shapes_json    formData  bool    false  "Описание фигур отдельным файлом shapes.json (только primitive)"
+// @Param        animation      formData  string  false  "Анимация построения рисунка: gif или frames (ZIP с кадрами PNG), только primitive"
+// @Param        frame_interval  formData  int     false  "Число фигур между кадрами анимации (по умолчанию около 50 кадров, не больше 200)"
+// @Param        frame_delay    formData  int     false  "Задержка между кадрами GIF, мс (20-5000, по умолчанию 100)"
+// @Param        callback_url     formData  string  false  "URL для вебхука по завершении задачи"
+// @Param        callback_secret  formData  string  false  "Секрет для подписи вебхука (HMAC-SHA256, заголовок X-Webhook-Signature)"
+// @Param        blur_sigma     formData  number   false  "Размытие для карандашного рисунка (0.5-50)"
+// @Param        paper_texture  formData  boolean  false  "Текстура бумаги для карандашного рисунка"
+// @Param        hatching       formData  boolean  false  "Штриховка тёмных областей для карандашного рисунка"
+// @Param        variants       formData  string   false  "JSON-массив вариантов результата, до 8: [{\"name\":\"small\",\"output_size\":512},{\"style\":\"pencil\"}]; в каждом только отличающиеся параметры"
+// @Param        no_cache       formData  boolean  false  "Не брать готовый результат из кэша (тот же файл с теми же параметрами), обработать заново"
 // @Success      200   {object}  models.UploadResponse  "Task создана, файл в S3"
-// @Failure      400   {object}  map[string]string      "Неверный файл"
+// @Failure      400   {object}  models.ImageErrorResponse  "Повреждённое изображение (code=corrupt_image), неверные параметры или source_url"
+// @Failure      413   {object}  models.ImageErrorResponse  "Файл или разрешение больше допустимого (code=image_too_large)"
+// @Failure      415   {object}  models.ImageErrorResponse  "Неподдерживаемый формат (code=unsupported_format)"
 // @Failure      500   {object}  map[string]string      "Ошибка сервера"
+// @Failure      502   {object}  map[string]string      "Источник недоступен"
 // @Router       /files [post]
 func (h *FilesHandler) UploadFileStreaming(c *gin.Context) {
 	logger := logging.LoggerFromContext(c.Request.Context())
 
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
+		if c.ContentType() == binding.MIMEJSON ||
+			c.PostForm("source_url") != "" {
+			h.uploadFromURL(c)
+			return
+		}
 		logger.Warn("missing file parameter in upload request")
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "file parameter is required",
+			"error": "file or source_url parameter is required",
+		})
+		return
+	}
+
+	var req models.CreateTaskRequest
+	if err := c.ShouldBind(&req); err != nil {
+		logger.Warn("invalid processing parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid processing parameters",
+		})
+		return
+	}
+
+	req.Variants, err = formVariants(c)
+	if err != nil {
+		logger.Warn("invalid variants", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "variants must be a JSON array of parameter objects",
 		})
 		return
 	}
@@ -52,9 +103,28 @@ func (h *FilesHandler) UploadFileStreaming(c *gin.Context) {
 	result, task, err := h.FileSrv.UploadFileStream(
 		c.Request.Context(),
 		fileHeader,
+		req.TaskOptions(),
 	)
 
 	if err != nil {
+		if imageError(c, err) {
+			return
+		}
+		if errors.Is(err, ut.ErrInvalidParams) ||
+			errors.Is(err, services.ErrInvalidCallback) {
+			logger.Warn("rejected processing parameters", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, services.ErrFileTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
 		logger.Error("failed to upload file to S3",
 			"error", err,
 			"file", fileHeader.Filename,
@@ -82,3 +152,160 @@ func (h *FilesHandler) UploadFileStreaming(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// uploadFromURL - загрузка по source_url (поле формы или JSON-тело)
+func (h *FilesHandler) uploadFromURL(c *gin.Context) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+
+	var req models.UploadURLRequest
+	if err := c.ShouldBind(&req); err != nil {
+		logger.Warn("invalid upload by URL request", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "source_url and valid processing parameters are required",
+		})
+		return
+	}
+	if c.ContentType() != binding.MIMEJSON {
+		variants, err := formVariants(c)
+		if err != nil {
+			logger.Warn("invalid variants", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "variants must be a JSON array of parameter objects",
+			})
+			return
+		}
+		req.Variants = variants
+	}
+
+	task, err := h.FileSrv.UploadFromURL(
+		c.Request.Context(),
+		req.SourceURL,
+		req.TaskOptions(),
+	)
+	if err != nil {
+		if imageError(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, ut.ErrInvalidParams),
+			errors.Is(err, services.ErrInvalidCallback),
+			errors.Is(err, services.ErrInvalidSourceURL),
+			errors.Is(err, services.ErrSourceBlocked):
+			logger.Warn("rejected upload by URL", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrFileTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrSourceNotImage):
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrSourceFetch):
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		default:
+			logger.Error("failed to upload file by URL", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to upload file",
+			})
+		}
+		return
+	}
+
+	logger.Info("file uploaded by URL",
+		"key", task.S3FileInfo.FileKey,
+		"task_id", task.ID,
+	)
+
+	c.JSON(http.StatusOK, &models.UploadResponse{
+		Message:    "File fetched successfully",
+		Key:        task.S3FileInfo.FileKey,
+		URL:        h.FileSrv.FileURL(task.S3FileInfo.FileKey),
+		Size:       task.S3FileInfo.Content.ContentLength,
+		TaskID:     task.ID,
+		TaskStatus: string(task.Status),
+	})
+}
+
+// CreateTaskForFile godoc
+// @Summary      Новая задача для уже загруженного файла
+// @Description  Обрабатывает сохранённый upload/{fileID} с другими параметрами без повторной загрузки; результат каждой задачи пишется в processed/{fileID}/{taskID}
+// @Tags         files
+// @Accept       application/json
+// @Accept       multipart/form-data
+// @Produce      application/json
+// @Param        fileID   path  string                    true  "ID загруженного файла"
+// @Param        request  body  models.CreateTaskRequest  true  "Параметры обработки и вебхук"
+// @Success      202  {object}  models.S3FileTask  "Задача создана"
+// @Failure      400  {object}  map[string]string  "Неверные параметры"
+// @Failure      404  {object}  map[string]string  "Файл не найден"
+// @Failure      500  {object}  map[string]string  "Ошибка сервера"
+// @Router       /files/{fileID}/tasks [post]
+func (h *FilesHandler) CreateTaskForFile(c *gin.Context) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+	fileID := c.Param("fileID")
+
+	var req models.CreateTaskRequest
+	if err := c.ShouldBind(&req); err != nil {
+		logger.Warn("invalid processing parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid processing parameters",
+		})
+		return
+	}
+	if c.ContentType() != binding.MIMEJSON {
+		variants, err := formVariants(c)
+		if err != nil {
+			logger.Warn("invalid variants", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "variants must be a JSON array of parameter objects",
+			})
+			return
+		}
+		req.Variants = variants
+	}
+
+	task, err := h.FileSrv.CreateTaskForFile(
+		c.Request.Context(),
+		fileID,
+		req.TaskOptions(),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, ut.ErrInvalidParams),
+			errors.Is(err, services.ErrInvalidCallback):
+			logger.Warn("rejected processing parameters", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrFileNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		default:
+			logger.Error("failed to create task for file",
+				"file_id", fileID,
+				"error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to create task",
+			})
+		}
+		return
+	}
+
+	logger.Info("task created for existing file",
+		"file_id", fileID,
+		"task_id", task.ID,
+		"style", task.Params.Style)
+	c.JSON(http.StatusAccepted, task.Redacted())
+}
+
+// formVariants - поле формы variants: JSON-массив вариантов
+func formVariants(c *gin.Context) ([]models.VariantSpec, error) {
+	raw := c.PostForm("variants")
+	if raw == "" {
+		return nil, nil
+	}
+
+	var variants []models.VariantSpec
+	if err := json.Unmarshal([]byte(raw), &variants); err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// imageError - ответ на отклонённое изображение: 415 для неизвестного
+// формата, 400 для повреждённого файла, 413 для слишком большого
+// разрешения. false, если err не связана с содержимым изображения
+func imageError(c *gin.Context, err error) bool {
+	var invalid *ut.InvalidImageError
+	if !errors.As(err, &invalid) {
+		return false
+	}
+
+	logging.LoggerFromContext(c.Request.Context()).
+		Warn("image rejected", "error", err)
+
+	resp := models.ImageErrorResponse{
+		Error:  err.Error(),
+		Format: invalid.Info.Format,
+	}
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(invalid.Reason, ut.ErrUnsupportedFormat):
+		status = http.StatusUnsupportedMediaType
+		resp.Code = "unsupported_format"
+		resp.Supported = ut.SupportedImageTypes()
+	case errors.Is(invalid.Reason, ut.ErrImageDimensions):
+		status = http.StatusRequestEntityTooLarge
+		resp.Code = "image_too_large"
+		resp.Width = invalid.Info.Width
+		resp.Height = invalid.Info.Height
+		resp.MaxWidth = invalid.Limits.MaxWidth
+		resp.MaxHeight = invalid.Limits.MaxHeight
+		resp.MaxPixels = invalid.Limits.MaxPixels
+	default:
+		resp.Code = "corrupt_image"
+	}
+
+	c.JSON(status, resp)
+	return true
+}
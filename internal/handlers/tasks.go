@@ -3,23 +3,31 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/BagRoman01/image-sketch-processor/internal/injectors"
 	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
 	"github.com/BagRoman01/image-sketch-processor/internal/services"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 
+const sseHeartbeatInterval = 15 * time.Second
+
 type TasksHandler struct {
-	TaskService *services.TaskService
+	TaskService    *services.TaskService
+	ArchiveService *services.ArchiveService
 }
 
 func NewTasksHandler(
 	serviceInjector *injectors.ServiceInjector,
 ) *TasksHandler {
 	return &TasksHandler{
-		TaskService: serviceInjector.TaskService,
+		TaskService:    serviceInjector.TaskService,
+		ArchiveService: serviceInjector.ArchiveService,
 	}
 }
 
@@ -38,10 +46,13 @@ func (h *TasksHandler) GetTaskStatus(c *gin.Context) {
 
 	task, err := h.TaskService.GetTask(c.Request.Context(), taskID)
 	if err != nil {
-		if errors.Is(err, fmt.Errorf("task not found")) {
+		if errors.Is(err, services.ErrTaskNotFound) {
 			logger.Warn("task not found", "task_id", taskID, "error", err)
+			c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		logger.Error("failed to get task", "task_id", taskID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -50,5 +61,192 @@ func (h *TasksHandler) GetTaskStatus(c *gin.Context) {
 		"task_id", taskID,
 		"status", task.Status,
 	)
-	c.JSON(http.StatusOK, task)
+	c.JSON(http.StatusOK, task.Redacted())
+}
+
+// StreamTaskEvents godoc
+// @Summary      Поток событий задачи (SSE)
+// @Description  Сразу отправляет текущее состояние задачи, затем смены статуса и прогресс до завершения
+// @Tags         tasks
+// @Produce      text/event-stream
+// @Param        id  path  string  true  "ID задачи"
+// @Success      200  {object}  models.TaskEvent "События status и progress"
+// @Failure      404  {object}  map[string]string "Задача не найдена"
+// @Router       /tasks/{id}/events [get]
+func (h *TasksHandler) StreamTaskEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+	logger := logging.LoggerFromContext(ctx)
+	taskID := c.Param("id")
+
+	// подписываемся до чтения задачи, чтобы не пропустить переход
+	events, closeEvents, err := h.TaskService.SubscribeTaskEvents(ctx, taskID)
+	if err != nil {
+		logger.Error("failed to subscribe to task events",
+			"task_id", taskID,
+			"error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to subscribe to task events",
+		})
+		return
+	}
+	defer closeEvents()
+
+	task, err := h.TaskService.GetTask(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	logger.Info("task event stream opened", "task_id", taskID)
+
+	c.SSEvent(string(models.TaskEventStatus), models.TaskEvent{
+		Type: models.TaskEventStatus,
+		Task: task.Redacted(),
+	})
+	c.Writer.Flush()
+	if task.Status.IsFinal() {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-heartbeat.C:
+			// комментарий держит соединение открытым через прокси
+			_, err := fmt.Fprint(w, ": ping\n\n")
+			return err == nil
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(string(event.Type), event)
+			return event.Task == nil || !event.Task.Status.IsFinal()
+		}
+	})
+
+	logger.Info("task event stream closed", "task_id", taskID)
+}
+
+// CancelTask godoc
+// @Summary      Отменить задачу
+// @Description  Ожидающая задача будет пропущена воркером, выполняющаяся - прервана, частичный результат удалён
+// @Tags         tasks
+// @Produce      application/json
+// @Param        id  path  string  true  "ID задачи"
+// @Success      200  {object}  models.S3FileTask "Задача отменена"
+// @Failure      404  {object}  map[string]string "Задача не найдена"
+// @Failure      409  {object}  map[string]string "Задача уже завершена"
+// @Router       /tasks/{id} [delete]
+func (h *TasksHandler) CancelTask(c *gin.Context) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+	taskID := c.Param("id")
+
+	task, err := h.TaskService.CancelTask(c.Request.Context(), taskID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTaskNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		case errors.Is(err, services.ErrTaskNotCancellable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			logger.Error("failed to cancel task",
+				"task_id", taskID,
+				"error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to cancel task",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, task.Redacted())
+}
+
+// RetryTask godoc
+// @Summary      Повторить задачу
+// @Description  Перезапускает задачу в статусе failed или cancelled с тем же загруженным файлом. Прошлый запуск сохраняется в history
+// @Tags         tasks
+// @Accept       application/json
+// @Produce      application/json
+// @Param        id       path  string                   true   "ID задачи"
+// @Param        request  body  models.RetryTaskRequest  false  "Новые параметры обработки"
+// @Success      202  {object}  models.S3FileTask "Задача поставлена в очередь"
+// @Failure      400  {object}  map[string]string "Неверные параметры"
+// @Failure      404  {object}  map[string]string "Задача не найдена"
+// @Failure      409  {object}  map[string]string "Задача не в статусе failed или cancelled"
+// @Router       /tasks/{id}/retry [post]
+func (h *TasksHandler) RetryTask(c *gin.Context) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+	taskID := c.Param("id")
+
+	var req models.RetryTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		logger.Warn("invalid retry request", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid processing parameters",
+		})
+		return
+	}
+
+	task, err := h.TaskService.RetryTask(
+		c.Request.Context(),
+		taskID,
+		req.Params,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTaskNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		case errors.Is(err, services.ErrTaskNotRetryable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, ut.ErrInvalidParams):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			logger.Error("failed to retry task",
+				"task_id", taskID,
+				"error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to retry task",
+			})
+		}
+		return
+	}
+
+	logger.Info("task retried", "task_id", taskID, "run", task.Run)
+	c.JSON(http.StatusAccepted, task.Redacted())
+}
+
+// DownloadTaskArchive godoc
+// @Summary      Скачать результаты задачи архивом
+// @Description  ZIP со всеми завершёнными результатами задачи (основным или вариантами) и manifest.json с исходным именем и параметрами каждого файла. Архив передаётся потоком из S3
+// @Tags         tasks
+// @Produce      application/zip
+// @Param        id  path  string  true  "ID задачи"
+// @Success      200  {file}    binary             "ZIP-архив"
+// @Failure      404  {object}  map[string]string  "Задача не найдена"
+// @Failure      409  {object}  map[string]string  "Нет завершённых результатов"
+// @Router       /tasks/{id}/archive [get]
+func (h *TasksHandler) DownloadTaskArchive(c *gin.Context) {
+	taskID := c.Param("id")
+
+	archive, err := h.ArchiveService.TaskArchive(c.Request.Context(), taskID)
+	if err != nil {
+		archiveError(c, err, services.ErrTaskNotFound, "task")
+		return
+	}
+
+	writeArchive(c, h.ArchiveService, archive)
 }
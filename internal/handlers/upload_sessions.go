@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/injectors"
+	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/BagRoman01/image-sketch-processor/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type UploadSessionsHandler struct {
+	SessionService *services.UploadSessionService
+}
+
+func NewUploadSessionsHandler(
+	serviceInjector *injectors.ServiceInjector,
+) *UploadSessionsHandler {
+	return &UploadSessionsHandler{
+		SessionService: serviceInjector.UploadService,
+	}
+}
+
+// CreateSession godoc
+// @Summary      Начать возобновляемую загрузку
+// @Description  Создаёт сессию загрузки по частям (S3 multipart upload). Файл делится на total_parts частей по part_size байт, последняя может быть меньше
+// @Tags         upload-sessions
+// @Accept       application/json
+// @Produce      application/json
+// @Param        request  body  models.UploadSessionRequest  true  "Имя, тип и размер файла"
+// @Success      201  {object}  models.UploadSessionResponse  "Сессия создана"
+// @Failure      400  {object}  map[string]string             "Неверный запрос"
+// @Failure      413  {object}  map[string]string             "Файл больше допустимого размера"
+// @Failure      415  {object}  map[string]string             "Тип файла не image/*"
+// @Router       /upload-sessions [post]
+func (h *UploadSessionsHandler) CreateSession(c *gin.Context) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+
+	var req models.UploadSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warn("invalid upload session request", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "file_name, content_type and size are required",
+		})
+		return
+	}
+
+	session, err := h.SessionService.CreateSession(c.Request.Context(), req)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// GetSession godoc
+// @Summary      Состояние загрузки
+// @Description  Принятые части и номера частей, которые ещё нужно отправить; используется для продолжения после обрыва
+// @Tags         upload-sessions
+// @Produce      application/json
+// @Param        id  path  string  true  "ID сессии"
+// @Success      200  {object}  models.UploadSessionResponse  "Сессия"
+// @Failure      404  {object}  map[string]string             "Сессия не найдена или истекла"
+// @Router       /upload-sessions/{id} [get]
+func (h *UploadSessionsHandler) GetSession(c *gin.Context) {
+	session, err := h.SessionService.GetSession(
+		c.Request.Context(),
+		c.Param("id"),
+	)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// UploadPart godoc
+// @Summary      Загрузить часть файла
+// @Description  Тело запроса - байты части. Размер должен быть равен part_size (для последней части - остатку). Повторная отправка заменяет часть
+// @Tags         upload-sessions
+// @Accept       application/octet-stream
+// @Produce      application/json
+// @Param        id      path  string  true  "ID сессии"
+// @Param        number  path  int     true  "Номер части, с 1"
+// @Success      200  {object}  models.UploadPart  "Часть принята"
+// @Failure      400  {object}  map[string]string  "Неверный номер или размер части"
+// @Failure      404  {object}  map[string]string  "Сессия не найдена или истекла"
+// @Router       /upload-sessions/{id}/parts/{number} [put]
+func (h *UploadSessionsHandler) UploadPart(c *gin.Context) {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "part number must be an integer",
+		})
+		return
+	}
+
+	part, err := h.SessionService.UploadPart(
+		c.Request.Context(),
+		c.Param("id"),
+		number,
+		c.Request.Body,
+	)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, part)
+}
+
+// CompleteSession godoc
+// @Summary      Завершить возобновляемую загрузку
+// @Description  Собирает файл из частей и создаёт задачу на обработку. Если задачу создать не удалось, файл остаётся загруженным и задачу можно создать через /uploads/{id}/finalize
+// @Tags         upload-sessions
+// @Accept       application/json
+// @Produce      application/json
+// @Param        id       path  string                    true  "ID сессии"
+// @Param        request  body  models.CreateTaskRequest  true  "Параметры обработки и вебхук"
+// @Success      202  {object}  models.S3FileTask  "Задача создана"
+// @Failure      400  {object}  models.ImageErrorResponse  "Неверные параметры или повреждённое изображение (code=corrupt_image)"
+// @Failure      404  {object}  map[string]string          "Сессия не найдена или истекла"
+// @Failure      409  {object}  map[string]string          "Загружены не все части"
+// @Failure      413  {object}  models.ImageErrorResponse  "Разрешение больше допустимого (code=image_too_large)"
+// @Failure      415  {object}  models.ImageErrorResponse  "Неподдерживаемый формат (code=unsupported_format)"
+// @Router       /upload-sessions/{id}/complete [post]
+func (h *UploadSessionsHandler) CompleteSession(c *gin.Context) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+
+	var req models.CreateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warn("invalid processing parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid processing parameters",
+		})
+		return
+	}
+
+	task, err := h.SessionService.CompleteSession(
+		c.Request.Context(),
+		c.Param("id"),
+		req.TaskOptions(),
+	)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, task.Redacted())
+}
+
+// AbortSession godoc
+// @Summary      Отменить возобновляемую загрузку
+// @Description  Удаляет сессию и уже загруженные части
+// @Tags         upload-sessions
+// @Param        id  path  string  true  "ID сессии"
+// @Success      204  "Загрузка отменена"
+// @Failure      404  {object}  map[string]string  "Сессия не найдена или истекла"
+// @Router       /upload-sessions/{id} [delete]
+func (h *UploadSessionsHandler) AbortSession(c *gin.Context) {
+	if err := h.SessionService.AbortSession(
+		c.Request.Context(),
+		c.Param("id"),
+	); err != nil {
+		uploadError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
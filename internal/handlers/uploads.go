@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/injectors"
+	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/BagRoman01/image-sketch-processor/internal/services"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type UploadsHandler struct {
+	FileSrv *services.FileService
+}
+
+func NewUploadsHandler(
+	serviceInjector *injectors.ServiceInjector,
+) *UploadsHandler {
+	return &UploadsHandler{
+		FileSrv: serviceInjector.FileService,
+	}
+}
+
+// PresignUpload godoc
+// @Summary      Ссылка для загрузки напрямую в S3
+// @Description  Возвращает presigned PUT (по умолчанию) или POST-политику для upload/{file_id}. Размер и Content-Type зафиксированы в подписи. После загрузки нужно вызвать /uploads/{file_id}/finalize
+// @Tags         uploads
+// @Accept       application/json
+// @Produce      application/json
+// @Param        request  body  models.PresignUploadRequest  true  "Имя, тип и размер файла"
+// @Success      201  {object}  models.PresignedUpload  "Параметры загрузки"
+// @Failure      400  {object}  map[string]string       "Неверный запрос"
+// @Failure      413  {object}  map[string]string       "Файл больше допустимого размера"
+// @Failure      415  {object}  map[string]string       "Тип файла не image/*"
+// @Failure      500  {object}  map[string]string       "Ошибка сервера"
+// @Router       /uploads [post]
+func (h *UploadsHandler) PresignUpload(c *gin.Context) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+
+	var req models.PresignUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warn("invalid presign request", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "file_name and content_type are required",
+		})
+		return
+	}
+
+	upload, err := h.FileSrv.PresignUpload(c.Request.Context(), req)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, upload)
+}
+
+// FinalizeUpload godoc
+// @Summary      Завершить прямую загрузку
+// @Description  Проверяет загруженный в S3 объект (размер, формат и разрешение по заголовку изображения) и создаёт задачу на обработку. Объект, не прошедший проверку, удаляется
+// @Tags         uploads
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fileID   path  string                    true  "ID файла из /uploads"
+// @Param        request  body  models.CreateTaskRequest  true  "Параметры обработки и вебхук"
+// @Success      202  {object}  models.S3FileTask  "Задача создана"
+// @Failure      400  {object}  models.ImageErrorResponse  "Неверные параметры или повреждённое изображение (code=corrupt_image)"
+// @Failure      404  {object}  map[string]string          "Файл ещё не загружен"
+// @Failure      413  {object}  models.ImageErrorResponse  "Файл или разрешение больше допустимого (code=image_too_large)"
+// @Failure      415  {object}  models.ImageErrorResponse  "Неподдерживаемый формат (code=unsupported_format)"
+// @Failure      500  {object}  map[string]string  "Ошибка сервера"
+// @Router       /uploads/{fileID}/finalize [post]
+func (h *UploadsHandler) FinalizeUpload(c *gin.Context) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+	fileID := c.Param("fileID")
+
+	var req models.CreateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warn("invalid processing parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid processing parameters",
+		})
+		return
+	}
+
+	task, err := h.FileSrv.FinalizeUpload(
+		c.Request.Context(),
+		fileID,
+		req.TaskOptions(),
+	)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, task.Redacted())
+}
+
+func uploadError(c *gin.Context, err error) {
+	logger := logging.LoggerFromContext(c.Request.Context())
+
+	if imageError(c, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, ut.ErrInvalidParams),
+		errors.Is(err, services.ErrInvalidCallback),
+		errors.Is(err, services.ErrInvalidUpload),
+		errors.Is(err, services.ErrInvalidPart):
+		logger.Warn("rejected upload request", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrFileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+	case errors.Is(err, services.ErrUploadSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "upload session not found"})
+	case errors.Is(err, services.ErrUploadIncomplete):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrFileTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUnsupportedMediaType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	default:
+		logger.Error("upload request failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to process upload request",
+		})
+	}
+}
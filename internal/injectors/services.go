@@ -7,6 +7,7 @@ import (
 
 	"github.com/BagRoman01/image-sketch-processor/internal/config"
 	"github.com/BagRoman01/image-sketch-processor/internal/messaging/rabbitmq"
+	"github.com/BagRoman01/image-sketch-processor/internal/processors"
 	"github.com/BagRoman01/image-sketch-processor/internal/repositories"
 	"github.com/BagRoman01/image-sketch-processor/internal/services"
 )
@@ -15,7 +16,13 @@ type ServiceInjector struct {
 	FileService       *services.FileService
 	TaskService       *services.TaskService
 	ProcessingService *services.ProcessingService
+	DeadLetterService *services.DeadLetterService
+	BatchService      *services.BatchService
+	ArchiveService    *services.ArchiveService
+	UploadService     *services.UploadSessionService
+	Processors        *processors.Registry
 
+	webhookService    *services.WebhookService
 	redisRepo         *repositories.RedisRepository
 	rabbitMQPublisher *rabbitmq.RabbitMQPublisher
 	rabbitMQConsumer  *rabbitmq.RabbitMQConsumer
@@ -84,8 +91,29 @@ func NewServiceInjector(
 		return nil, err
 	}
 
-	taskService := services.NewTaskService(redisRepo, rabbitmqPublisher)
-	fileService := services.NewFileService(s3repository, taskService)
+	processorRegistry := processors.NewDefaultRegistry()
+
+	webhookService := services.NewWebhookService(&cfg.WebhookConfig)
+	resultCache := services.NewResultCache(
+		&cfg.CacheConfig,
+		redisRepo,
+		s3repository,
+		processorRegistry,
+	)
+	taskService := services.NewTaskService(
+		redisRepo,
+		rabbitmqPublisher,
+		webhookService,
+		processorRegistry,
+		resultCache,
+	)
+	fileService := services.NewFileService(
+		s3repository,
+		taskService,
+		processorRegistry,
+		services.NewSourceFetcher(&cfg.FetchConfig),
+		&cfg.ImageConfig,
+	)
 
 	rabbitmqConsumer, err := rabbitmq.NewRabbitMQConsumer(
 		ctx,
@@ -97,9 +125,12 @@ func NewServiceInjector(
 
 	processingSrv, err := services.NewProcessingService(
 		ctx,
+		&cfg.ProcessingConfig,
 		fileService,
 		taskService,
+		processorRegistry,
 		rabbitmqConsumer,
+		resultCache,
 	)
 	if err != nil {
 		slog.Error("failed to create processing service!",
@@ -111,17 +142,46 @@ func NewServiceInjector(
 	return &ServiceInjector{
 		FileService:       fileService,
 		TaskService:       taskService,
+		Processors:        processorRegistry,
+		webhookService:    webhookService,
 		redisRepo:         redisRepo,
 		rabbitMQPublisher: rabbitmqPublisher,
 		rabbitMQConsumer:  rabbitmqConsumer,
 		s3Repo:            s3repository,
 		ProcessingService: processingSrv,
+		DeadLetterService: services.NewDeadLetterService(
+			rabbitmqPublisher,
+			taskService,
+		),
+		BatchService: services.NewBatchService(
+			&cfg.BatchConfig,
+			redisRepo,
+			fileService,
+			taskService,
+		),
+		ArchiveService: services.NewArchiveService(
+			redisRepo,
+			fileService,
+			taskService,
+		),
+		UploadService: services.NewUploadSessionService(
+			redisRepo,
+			s3repository,
+			fileService,
+		),
 	}, nil
 }
 
 func (i *ServiceInjector) Shutdown(ctx context.Context) error {
 	var errs []error
 
+	// дожидаемся вебхуков: они пишут попытки в Redis
+	if i.webhookService != nil {
+		if err := i.webhookService.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	if i.rabbitMQPublisher != nil {
 		if err := i.rabbitMQPublisher.Close(ctx); err != nil {
 			errs = append(errs, err)
@@ -64,6 +64,13 @@ func NewRabbitMQBase(
 		return nil, fmt.Errorf("RabbitMQ queue: %w", err)
 	}
 
+	if err := declareTopology(ch, cfg); err != nil {
+		ch.Close()
+		conn.Close()
+		slog.Error("RabbitMQ retry topology failed", "error", err)
+		return nil, fmt.Errorf("RabbitMQ topology: %w", err)
+	}
+
 	slog.Info("RabbitMQ base ready", "queue", cfg.QueueName)
 
 	return &RabbitMQBase{
@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/BagRoman01/image-sketch-processor/internal/config"
 	"github.com/BagRoman01/image-sketch-processor/internal/logging"
 	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/oklog/ulid/v2"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
@@ -57,38 +59,142 @@ func (c *RabbitMQConsumer) Consume(
 				return fmt.Errorf("delivery channel closed")
 			}
 
-			go func(delivery amqp.Delivery) {
-				task, err := c.unmarshalTask(delivery.Body)
-				if err != nil {
-					slog.Error("failed to unmarshal task", "error", err)
-					if err := delivery.Nack(false, false); err != nil {
-						slog.Error("failed to Nack message", "error", err)
-					}
-					return
-				}
-
-				taskCtx := context.TODO()
-				if err := handler(taskCtx, task); err != nil {
-					slog.Error("task handler failed",
-						"task_id", task.ID,
-						"error", err)
-					if nackErr := delivery.Nack(false, false); nackErr != nil {
-						slog.Error("failed to Nack message", "error", nackErr)
-					}
-					return
-				}
-
-				// Ack только после успешной обработки
-				if err := delivery.Ack(false); err != nil {
-					slog.Error("failed to Ack message",
-						"task_id", task.ID,
-						"error", err)
-				}
-			}(delivery)
+			go c.handleDelivery(ctx, delivery, handler)
 		}
 	}
 }
 
+// MaxAttempts - сколько раз сообщение обрабатывается до отправки в DLQ
+func (c *RabbitMQConsumer) MaxAttempts() int {
+	return max(c.cfg.MaxAttempts, 1)
+}
+
+// RetryDelay - через сколько сообщение вернётся после попытки attempt
+func (c *RabbitMQConsumer) RetryDelay(attempt int) time.Duration {
+	return retryDelay(c.cfg, attempt)
+}
+
+func (c *RabbitMQConsumer) handleDelivery(
+	ctx context.Context,
+	delivery amqp.Delivery,
+	handler func(ctx context.Context, task *models.S3FileTask) error,
+) {
+	// обработка и перепубликация не прерываются остановкой консьюмера
+	ctx = context.WithoutCancel(ctx)
+
+	task, err := c.unmarshalTask(delivery.Body)
+	if err != nil {
+		slog.Error("failed to unmarshal task", "error", err)
+		c.settle(ctx, delivery, c.deadLetter(ctx, delivery, "", 1, err))
+		return
+	}
+
+	// номер текущей попытки: повторы несут число уже сделанных
+	attempt := attemptFromHeaders(delivery.Headers) + 1
+	task.Attempts = attempt
+
+	err = handler(ctx, task)
+	if err == nil {
+		// Ack только после успешной обработки
+		c.settle(ctx, delivery, nil)
+		return
+	}
+
+	if IsRetryable(err) && attempt < c.MaxAttempts() {
+		slog.Warn("task handler failed, scheduling retry",
+			"task_id", task.ID,
+			"attempt", attempt,
+			"retry_in", retryDelay(c.cfg, attempt),
+			"error", err)
+		c.settle(ctx, delivery, c.retry(ctx, delivery, task.ID, attempt, err))
+		return
+	}
+
+	slog.Error("task handler failed, moving to dead-letter queue",
+		"task_id", task.ID,
+		"attempt", attempt,
+		"error", err)
+	c.settle(ctx, delivery, c.deadLetter(ctx, delivery, task.ID, attempt, err))
+}
+
+// settle - Ack, если сообщение обработано или переложено в другую очередь;
+// иначе Nack с возвратом в очередь, чтобы не потерять его
+func (c *RabbitMQConsumer) settle(
+	ctx context.Context,
+	delivery amqp.Delivery,
+	republishErr error,
+) {
+	if republishErr != nil {
+		slog.Error("failed to republish message, requeueing",
+			"error", republishErr)
+		if err := delivery.Nack(false, true); err != nil {
+			slog.Error("failed to Nack message", "error", err)
+		}
+		return
+	}
+
+	if err := delivery.Ack(false); err != nil {
+		slog.Error("failed to Ack message", "error", err)
+	}
+}
+
+// retry - публикация в очередь ожидания <queue>.retry.<attempt>
+func (c *RabbitMQConsumer) retry(
+	ctx context.Context,
+	delivery amqp.Delivery,
+	taskID string,
+	attempt int,
+	cause error,
+) error {
+	return c.channel.PublishWithContext(
+		ctx,
+		"", // exchange
+		retryQueueName(c.cfg, attempt),
+		false, // mandatory
+		false, // immediate
+		amqp.Publishing{
+			DeliveryMode: amqp.Persistent,
+			ContentType:  delivery.ContentType,
+			Headers: amqp.Table{
+				headerAttempt:   int32(attempt),
+				headerLastError: cause.Error(),
+				headerTaskID:    taskID,
+			},
+			Body: delivery.Body,
+		},
+	)
+}
+
+// deadLetter - публикация в DLX с причиной и числом попыток
+func (c *RabbitMQConsumer) deadLetter(
+	ctx context.Context,
+	delivery amqp.Delivery,
+	taskID string,
+	attempt int,
+	cause error,
+) error {
+	return c.channel.PublishWithContext(
+		ctx,
+		deadLetterExchangeName(c.cfg),
+		c.cfg.QueueName, // routing key
+		false,           // mandatory
+		false,           // immediate
+		amqp.Publishing{
+			DeliveryMode: amqp.Persistent,
+			ContentType:  delivery.ContentType,
+			MessageId:    ulid.Make().String(),
+			Timestamp:    time.Now().UTC(),
+			Headers: amqp.Table{
+				headerAttempt:   int32(attempt),
+				headerLastError: cause.Error(),
+				headerFailedAt:  time.Now().UTC(),
+				headerTaskID:    taskID,
+			},
+			Body: delivery.Body,
+		},
+	)
+}
+
 func (c *RabbitMQConsumer) unmarshalTask(
 	body []byte,
 ) (*models.S3FileTask, error) {
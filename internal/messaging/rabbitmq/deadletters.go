@@ -0,0 +1,99 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// DeadLetterVisitor - решает по сообщению, забрать ли его из очереди
+// (take) и прекратить ли обход (stop)
+type DeadLetterVisitor func(dl models.DeadLetter) (take, stop bool)
+
+// VisitDeadLetters - обход не более limit сообщений dead-letter очереди
+// через basic.get на отдельном канале. Забранные сообщения подтверждаются,
+// остальные возвращаются в очередь при закрытии канала
+func (p *RabbitMQPublisher) VisitDeadLetters(
+	ctx context.Context,
+	limit int,
+	visit DeadLetterVisitor,
+) error {
+	logger := logging.LoggerFromContext(ctx)
+	queue := DeadLetterQueueName(p.cfg)
+
+	ch, err := p.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("open dead-letter channel: %w", err)
+	}
+	defer func() {
+		if err := ch.Close(); err != nil {
+			logger.Warn("failed to close dead-letter channel",
+				"queue", queue,
+				"error", err)
+		}
+	}()
+
+	for seen := 0; limit <= 0 || seen < limit; seen++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		delivery, ok, err := ch.Get(queue, false)
+		if err != nil {
+			return fmt.Errorf("get from %s: %w", queue, err)
+		}
+		if !ok {
+			return nil
+		}
+
+		take, stop := visit(decodeDeadLetter(delivery))
+		if take {
+			if err := delivery.Ack(false); err != nil {
+				return fmt.Errorf("ack dead letter %q: %w",
+					delivery.MessageId, err)
+			}
+		}
+		if stop {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func decodeDeadLetter(delivery amqp.Delivery) models.DeadLetter {
+	dl := models.DeadLetter{
+		MessageID: delivery.MessageId,
+		Attempts:  attemptFromHeaders(delivery.Headers),
+		FailedAt:  delivery.Timestamp,
+	}
+	if v, ok := delivery.Headers[headerTaskID].(string); ok {
+		dl.TaskID = v
+	}
+	if v, ok := delivery.Headers[headerLastError].(string); ok {
+		dl.LastError = v
+	}
+	if v, ok := delivery.Headers[headerFailedAt].(time.Time); ok {
+		dl.FailedAt = v
+	}
+
+	var task models.S3FileTask
+	if err := json.Unmarshal(delivery.Body, &task); err != nil {
+		dl.Body = string(delivery.Body)
+		return dl
+	}
+	dl.Task = &task
+	if dl.TaskID == "" {
+		dl.TaskID = task.ID
+	}
+	return dl
+}
+
+func (p *RabbitMQPublisher) DeadLetterQueue() string {
+	return DeadLetterQueueName(p.cfg)
+}
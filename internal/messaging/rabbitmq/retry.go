@@ -0,0 +1,121 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/config"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	headerAttempt   = "x-attempt"
+	headerLastError = "x-last-error"
+	headerFailedAt  = "x-failed-at"
+	headerTaskID    = "x-task-id"
+)
+
+// retryableError - временная ошибка: сообщение будет повторено с задержкой
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// Retryable - пометить ошибку обработчика как временную
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err}
+}
+
+func IsRetryable(err error) bool {
+	var re *retryableError
+	return errors.As(err, &re)
+}
+
+// retryDelay - задержка перед повтором после попытки attempt (с 1)
+func retryDelay(cfg *config.RabbitMQConfig, attempt int) time.Duration {
+	delay := time.Duration(cfg.RetryBaseDelayMs) * time.Millisecond
+	maxDelay := time.Duration(cfg.RetryMaxDelayMs) * time.Millisecond
+
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+func retryQueueName(cfg *config.RabbitMQConfig, attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", cfg.QueueName, attempt)
+}
+
+func deadLetterExchangeName(cfg *config.RabbitMQConfig) string {
+	return cfg.QueueName + ".dlx"
+}
+
+func DeadLetterQueueName(cfg *config.RabbitMQConfig) string {
+	return cfg.QueueName + ".dlq"
+}
+
+// attemptFromHeaders - номер уже сделанных попыток из заголовков сообщения
+func attemptFromHeaders(headers map[string]any) int {
+	switch v := headers[headerAttempt].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+// declareTopology - очереди повторов с TTL по числу попыток и
+// dead-letter exchange/очередь для исчерпанных и неисправимых сообщений.
+// Просроченное сообщение из <queue>.retry.N возвращается в основную очередь
+func declareTopology(ch *amqp.Channel, cfg *config.RabbitMQConfig) error {
+	dlx := deadLetterExchangeName(cfg)
+	if err := ch.ExchangeDeclare(
+		dlx,
+		amqp.ExchangeDirect,
+		true,  // durable
+		false, // auto-deleted
+		false, // internal
+		false, // no-wait
+		nil,
+	); err != nil {
+		return fmt.Errorf("declare dead-letter exchange %s: %w", dlx, err)
+	}
+
+	dlq := DeadLetterQueueName(cfg)
+	if _, err := ch.QueueDeclare(dlq, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("declare dead-letter queue %s: %w", dlq, err)
+	}
+	if err := ch.QueueBind(dlq, cfg.QueueName, dlx, false, nil); err != nil {
+		return fmt.Errorf("bind dead-letter queue %s: %w", dlq, err)
+	}
+
+	for attempt := 1; attempt < cfg.MaxAttempts; attempt++ {
+		name := retryQueueName(cfg, attempt)
+		if _, err := ch.QueueDeclare(
+			name,
+			true,  // durable
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			amqp.Table{
+				"x-message-ttl":             retryDelay(cfg, attempt).Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": cfg.QueueName,
+			},
+		); err != nil {
+			return fmt.Errorf("declare retry queue %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// ArchiveManifest - manifest.json внутри архива с результатами
+type ArchiveManifest struct {
+	Kind        string        `json:"kind"`
+	ID          string        `json:"id"`
+	GeneratedAt time.Time     `json:"generated_at"`
+	Files       []ArchiveFile `json:"files"`
+	Skipped     []ArchiveSkip `json:"skipped,omitempty"`
+}
+
+// ArchiveFile - файл архива и задача, результатом которой он является
+type ArchiveFile struct {
+	Path         string           `json:"path"`
+	TaskID       string           `json:"task_id"`
+	Variant      string           `json:"variant,omitempty"`
+	Artifact     string           `json:"artifact,omitempty"`
+	SourceName   string           `json:"source_name"`
+	ProcessedKey string           `json:"processed_key"`
+	ContentType  string           `json:"content_type,omitempty"`
+	Size         int64            `json:"size"`
+	Params       ProcessingParams `json:"params"`
+}
+
+// ArchiveSkip - результат, не попавший в архив
+type ArchiveSkip struct {
+	TaskID     string     `json:"task_id"`
+	Variant    string     `json:"variant,omitempty"`
+	SourceName string     `json:"source_name,omitempty"`
+	Status     TaskStatus `json:"status,omitempty"`
+	Reason     string     `json:"reason"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+type BatchStatus string
+
+const (
+	BatchStatusPending    BatchStatus = "pending"
+	BatchStatusProcessing BatchStatus = "processing"
+	BatchStatusCompleted  BatchStatus = "completed"
+	// BatchStatusPartial - все задачи завершены, но не все успешно
+	BatchStatusPartial BatchStatus = "completed_with_errors"
+	BatchStatusFailed  BatchStatus = "failed"
+)
+
+// Batch - набор задач, созданных одной пакетной загрузкой
+type Batch struct {
+	ID        string           `json:"id"`
+	CreatedAt time.Time        `json:"created_at"`
+	TaskIDs   []string         `json:"task_ids"`
+	Rejected  []RejectedFile   `json:"rejected,omitempty"`
+	Params    ProcessingParams `json:"params"`
+}
+
+// RejectedFile - файл пакета, для которого задача не создана
+type RejectedFile struct {
+	FileName string `json:"file_name"`
+	Error    string `json:"error"`
+}
+
+type BatchTaskSummary struct {
+	TaskID       string     `json:"task_id"`
+	FileName     string     `json:"file_name,omitempty"`
+	Status       TaskStatus `json:"status"`
+	Percent      float64    `json:"percent,omitempty"`
+	ProcessedKey string     `json:"processed_key,omitempty"`
+	DownloadURL  string     `json:"download_url,omitempty"`
+	Variants     int        `json:"variants,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// BatchResponse - пакет со сводкой по дочерним задачам
+type BatchResponse struct {
+	ID        string             `json:"id"`
+	Status    BatchStatus        `json:"status"`
+	CreatedAt time.Time          `json:"created_at"`
+	Total     int                `json:"total"`
+	Counts    map[TaskStatus]int `json:"counts"`
+	// Expired - задачи, чьи записи уже удалены из Redis по TTL
+	Expired  int                `json:"expired,omitempty"`
+	Tasks    []BatchTaskSummary `json:"tasks"`
+	Rejected []RejectedFile     `json:"rejected,omitempty"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// CachedResult - готовый результат обработки, который можно отдать
+// задаче с тем же исходником и параметрами
+type CachedResult struct {
+	ProcessedKey string         `json:"processed_key"`
+	MimeType     string         `json:"mime_type,omitempty"`
+	Size         int64          `json:"size,omitempty"`
+	Width        int            `json:"width,omitempty"`
+	Height       int            `json:"height,omitempty"`
+	Artifacts    []TaskArtifact `json:"artifacts,omitempty"`
+	TaskID       string         `json:"task_id"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// DeadLetter - сообщение из dead-letter очереди. Task пуст, если тело
+// сообщения не удалось разобрать; тогда исходное тело лежит в Body
+type DeadLetter struct {
+	MessageID string      `json:"message_id"`
+	TaskID    string      `json:"task_id,omitempty"`
+	LastError string      `json:"last_error,omitempty"`
+	Attempts  int         `json:"attempts"`
+	FailedAt  time.Time   `json:"failed_at"`
+	Task      *S3FileTask `json:"task,omitempty"`
+	Body      string      `json:"body,omitempty"`
+}
+
+type DeadLettersResponse struct {
+	Queue    string       `json:"queue"`
+	Messages []DeadLetter `json:"messages"`
+}
+
+type DeadLetterReplayRequest struct {
+	MessageIDs []string `json:"message_ids"`
+	All        bool     `json:"all"`
+}
+
+type DeadLetterReplayResult struct {
+	MessageID string `json:"message_id"`
+	TaskID    string `json:"task_id,omitempty"`
+	Replayed  bool   `json:"replayed"`
+	Error     string `json:"error,omitempty"`
+}
+
+type DeadLetterReplayResponse struct {
+	Replayed int                      `json:"replayed"`
+	Results  []DeadLetterReplayResult `json:"results"`
+}
@@ -0,0 +1,21 @@
+package models
+
+type EffectParam struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"` // int, number, string, bool
+	Description string   `json:"description"`
+	Default     any      `json:"default,omitempty"`
+	Min         *float64 `json:"min,omitempty"`
+	Max         *float64 `json:"max,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+}
+
+type EffectInfo struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Params      []EffectParam `json:"params"`
+}
+
+type EffectsResponse struct {
+	Effects []EffectInfo `json:"effects"`
+}
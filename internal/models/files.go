@@ -9,6 +9,41 @@ type UploadResponse struct {
 	TaskStatus string `json:"task_status"`
 }
 
+// CreateTaskRequest - новая задача над уже загруженным файлом
+// (JSON или поля формы, как при загрузке)
+type CreateTaskRequest struct {
+	ProcessingParams
+	CallbackURL    string `json:"callback_url" form:"callback_url"`
+	CallbackSecret string `json:"callback_secret" form:"callback_secret"`
+	// В форме variants передаётся JSON-массивом в одном поле
+	Variants []VariantSpec `json:"variants,omitempty" form:"-"`
+	NoCache  bool          `json:"no_cache" form:"no_cache"`
+}
+
+// Callback - адрес вебхука из запроса, nil если не задан
+func (r *CreateTaskRequest) Callback() *Callback {
+	if r.CallbackURL == "" {
+		return nil
+	}
+	return &Callback{URL: r.CallbackURL, Secret: r.CallbackSecret}
+}
+
+// TaskOptions - параметры создаваемой задачи из запроса
+func (r *CreateTaskRequest) TaskOptions() TaskOptions {
+	return TaskOptions{
+		Params:   r.ProcessingParams,
+		Variants: r.Variants,
+		Callback: r.Callback(),
+		NoCache:  r.NoCache,
+	}
+}
+
+// UploadURLRequest - загрузка исходника по ссылке вместо файла
+type UploadURLRequest struct {
+	CreateTaskRequest
+	SourceURL string `json:"source_url" form:"source_url" binding:"required"`
+}
+
 type FileInfo struct {
 	FileName string  `json:"file_name"`
 	Content  Content `json:"content"`
@@ -18,8 +53,19 @@ type S3FileInfo struct {
 	FileInfo
 	FileKey string `json:"file_key"`
 	FileID  string `json:"file_id"`
+	// SourceURL - откуда скачан исходник, если он загружен по source_url
+	SourceURL string `json:"source_url,omitempty"`
+	// SHA256 - хэш содержимого, посчитанный при загрузке (hex)
+	SHA256 string `json:"sha256,omitempty"`
 }
 type Content struct {
 	ContentLength int64  `json:"content_size"`
 	ContentType   string `json:"content_type"`
 }
+
+// ObjectAttrs - заголовки и пользовательские метаданные объекта в S3
+type ObjectAttrs struct {
+	ContentType        string
+	ContentDisposition string
+	Metadata           map[string]string
+}
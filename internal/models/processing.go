@@ -0,0 +1,86 @@
+package models
+
+// ProcessingParams - параметры обработки, переданные клиентом при загрузке.
+// Пустые поля означают "взять значение из стиля или по умолчанию".
+type ProcessingParams struct {
+	Effect     string `json:"effect,omitempty" form:"effect"`
+	Style      string `json:"style,omitempty" form:"style"`
+	NumShapes  *int   `json:"num_shapes,omitempty" form:"num_shapes"`
+	Mode       *int   `json:"mode,omitempty" form:"mode"`
+	Alpha      *int   `json:"alpha,omitempty" form:"alpha"`
+	Background string `json:"background,omitempty" form:"background"`
+	OutputSize *int   `json:"output_size,omitempty" form:"output_size"`
+
+	// Параметры карандашного рисунка (стили pencil, pencil-hatched)
+	BlurSigma    *float64 `json:"blur_sigma,omitempty" form:"blur_sigma"`
+	PaperTexture *bool    `json:"paper_texture,omitempty" form:"paper_texture"`
+	Hatching     *bool    `json:"hatching,omitempty" form:"hatching"`
+
+	// Формат результата: png, jpg, webp, svg, gif; quality - для jpg
+	OutputFormat string `json:"output_format,omitempty" form:"output_format"`
+	Quality      *int   `json:"quality,omitempty" form:"quality"`
+	// ShapesJSON - описание фигур файлом shapes.json рядом с результатом
+	ShapesJSON *bool `json:"shapes_json,omitempty" form:"shapes_json"`
+
+	// Анимация построения рисунка: gif или frames (ZIP с PNG);
+	// frame_interval - фигур между кадрами, frame_delay - мс между кадрами
+	Animation     string `json:"animation,omitempty" form:"animation"`
+	FrameInterval *int   `json:"frame_interval,omitempty" form:"frame_interval"`
+	FrameDelay    *int   `json:"frame_delay,omitempty" form:"frame_delay"`
+}
+
+// Merge - параметры варианта поверх базовых. Стиль без явного эффекта
+// сбрасывает базовый эффект, чтобы эффект определился по стилю
+func (p ProcessingParams) Merge(override ProcessingParams) ProcessingParams {
+	merged := p
+	if override.Style != "" {
+		merged.Style = override.Style
+		merged.Effect = override.Effect
+	}
+	if override.Effect != "" {
+		merged.Effect = override.Effect
+	}
+	if override.NumShapes != nil {
+		merged.NumShapes = override.NumShapes
+	}
+	if override.Mode != nil {
+		merged.Mode = override.Mode
+	}
+	if override.Alpha != nil {
+		merged.Alpha = override.Alpha
+	}
+	if override.Background != "" {
+		merged.Background = override.Background
+	}
+	if override.OutputSize != nil {
+		merged.OutputSize = override.OutputSize
+	}
+	if override.BlurSigma != nil {
+		merged.BlurSigma = override.BlurSigma
+	}
+	if override.PaperTexture != nil {
+		merged.PaperTexture = override.PaperTexture
+	}
+	if override.Hatching != nil {
+		merged.Hatching = override.Hatching
+	}
+	if override.OutputFormat != "" {
+		merged.OutputFormat = override.OutputFormat
+	}
+	if override.Quality != nil {
+		merged.Quality = override.Quality
+	}
+	if override.ShapesJSON != nil {
+		merged.ShapesJSON = override.ShapesJSON
+	}
+	if override.Animation != "" {
+		merged.Animation = override.Animation
+	}
+	if override.FrameInterval != nil {
+		merged.FrameInterval = override.FrameInterval
+	}
+	if override.FrameDelay != nil {
+		merged.FrameDelay = override.FrameDelay
+	}
+	return merged
+}
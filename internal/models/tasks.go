@@ -9,6 +9,7 @@ const (
 	TaskStatusProcessing TaskStatus = "processing"
 	TaskStatusCompleted  TaskStatus = "completed"
 	TaskStatusFailed     TaskStatus = "failed"
+	TaskStatusCancelled  TaskStatus = "cancelled"
 )
 
 type Task struct {
@@ -18,11 +19,139 @@ type Task struct {
 	UpdatedAt   time.Time  `json:"updated_at"`
 	CompletedAt time.Time  `json:"completed_at,omitempty"`
 	Error       string     `json:"error,omitempty"`
+	// Run - номер запуска, растёт при каждом повторе задачи;
+	// Attempts - номер текущей попытки внутри запуска
+	Run         int        `json:"run"`
+	Attempts    int        `json:"attempts,omitempty"`
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+}
+
+// TaskProgress - ход обработки: шаги (для primitive - фигуры) из общего числа
+type TaskProgress struct {
+	Done       int       `json:"done"`
+	Total      int       `json:"total"`
+	Percent    float64   `json:"percent"`
+	Score      float64   `json:"score,omitempty"`
+	ETASeconds float64   `json:"eta_seconds"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Callback - адрес, на который воркер отправит результат задачи.
+// Secret используется для подписи и не отдаётся клиентам (см. Redacted)
+type Callback struct {
+	URL    string `json:"url" form:"callback_url"`
+	Secret string `json:"secret,omitempty" form:"callback_secret"`
+}
+
+type CallbackAttempt struct {
+	Attempt    int       `json:"attempt"`
+	Event      string    `json:"event"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	At         time.Time `json:"at"`
+}
+
+// VariantSpec - вариант результата, запрошенный клиентом: параметры
+// задаются только отличающиеся от основных
+type VariantSpec struct {
+	Name string `json:"name,omitempty"`
+	ProcessingParams
+}
+
+// TaskOptions - всё, что клиент задаёт при создании задачи
+type TaskOptions struct {
+	Params   ProcessingParams
+	Variants []VariantSpec
+	Callback *Callback
+	BatchID  string
+	// NoCache - не брать готовый результат из кэша, обработать заново
+	NoCache bool
+}
+
+// TaskVariant - вариант результата задачи. Params хранит только
+// переопределения, итоговые параметры - Params задачи с ними поверх
+type TaskVariant struct {
+	Name         string           `json:"name"`
+	Params       ProcessingParams `json:"params"`
+	Status       TaskStatus       `json:"status"`
+	ProcessedKey string           `json:"processed_key,omitempty"`
+	MimeType     string           `json:"mime_type,omitempty"`
+	Size         int64            `json:"size,omitempty"`
+	Width        int              `json:"width,omitempty"`
+	Height       int              `json:"height,omitempty"`
+	DownloadURL  string           `json:"download_url,omitempty"`
+	Artifacts    []TaskArtifact   `json:"artifacts,omitempty"`
+	CacheHit     bool             `json:"cache_hit,omitempty"`
+	Error        string           `json:"error,omitempty"`
+}
+
+// TaskArtifact - дополнительный файл результата (например, shapes.json)
+type TaskArtifact struct {
+	Name        string `json:"name" example:"shapes"`
+	Key         string `json:"key"`
+	MimeType    string `json:"mime_type"`
+	Size        int64  `json:"size"`
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// TaskRun - итог предыдущего запуска задачи, сохраняется при повторе
+type TaskRun struct {
+	Run        int              `json:"run"`
+	Status     TaskStatus       `json:"status"`
+	Attempts   int              `json:"attempts,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Params     ProcessingParams `json:"params"`
+	FinishedAt time.Time        `json:"finished_at"`
 }
 
 type S3FileTask struct {
 	Task
-	ProcessedKey string     `json:"processed_key,omitempty"`
-	DownloadURL  string     `json:"download_url,omitempty"`
-	S3FileInfo   S3FileInfo `json:"file_info"`
+	Progress         *TaskProgress     `json:"progress,omitempty"`
+	ProcessedKey     string            `json:"processed_key,omitempty"`
+	DownloadURL      string            `json:"download_url,omitempty"`
+	Artifacts        []TaskArtifact    `json:"artifacts,omitempty"`
+	S3FileInfo       S3FileInfo        `json:"file_info"`
+	BatchID          string            `json:"batch_id,omitempty"`
+	NoCache          bool              `json:"no_cache,omitempty"`
+	CacheHit         bool              `json:"cache_hit,omitempty"`
+	Params           ProcessingParams  `json:"params"`
+	Variants         []TaskVariant     `json:"variants,omitempty"`
+	Callback         *Callback         `json:"callback,omitempty"`
+	CallbackAttempts []CallbackAttempt `json:"callback_attempts,omitempty"`
+	History          []TaskRun         `json:"history,omitempty"`
+}
+
+// Redacted - копия задачи без секретов для ответа клиенту
+func (t *S3FileTask) Redacted() *S3FileTask {
+	c := *t
+	if t.Callback != nil {
+		c.Callback = &Callback{URL: t.Callback.URL}
+	}
+	return &c
+}
+
+// RetryTaskRequest - тело POST /tasks/{id}/retry; params, если заданы,
+// полностью заменяют параметры прошлого запуска
+type RetryTaskRequest struct {
+	Params *ProcessingParams `json:"params"`
+}
+
+type TaskEventType string
+
+const (
+	TaskEventStatus   TaskEventType = "status"
+	TaskEventProgress TaskEventType = "progress"
+)
+
+// TaskEvent - изменение задачи, рассылаемое подписчикам (SSE)
+type TaskEvent struct {
+	Type TaskEventType `json:"type"`
+	Task *S3FileTask   `json:"task"`
+}
+
+func (s TaskStatus) IsFinal() bool {
+	return s == TaskStatusCompleted ||
+		s == TaskStatusFailed ||
+		s == TaskStatusCancelled
 }
@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+type UploadMethod string
+
+const (
+	UploadMethodPut  UploadMethod = "put"
+	UploadMethodPost UploadMethod = "post"
+)
+
+// PresignUploadRequest - запрос ссылки для загрузки напрямую в S3
+type PresignUploadRequest struct {
+	FileName    string `json:"file_name" binding:"required"`
+	ContentType string `json:"content_type" binding:"required"`
+	// Size - точный размер файла, обязателен для PUT
+	Size   int64        `json:"size"`
+	Method UploadMethod `json:"method" enums:"put,post"`
+}
+
+// PresignedUpload - куда и как загрузить файл; после загрузки вызывается
+// POST /uploads/{file_id}/finalize
+type PresignedUpload struct {
+	FileID string       `json:"file_id"`
+	Key    string       `json:"key"`
+	Method UploadMethod `json:"method"`
+	URL    string       `json:"url"`
+	// Headers - заголовки, которые нужно отправить с PUT
+	Headers map[string]string `json:"headers,omitempty"`
+	// Fields - поля формы для POST, файл передаётся последним полем file
+	Fields    map[string]string `json:"fields,omitempty"`
+	MaxSize   int64             `json:"max_size"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// UploadSession - возобновляемая загрузка по частям поверх S3 multipart
+// upload; части можно отправлять повторно и в любом порядке
+type UploadSession struct {
+	ID          string `json:"id"`
+	Key         string `json:"key"`
+	S3UploadID  string `json:"s3_upload_id"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// PartSize - размер каждой части, кроме последней
+	PartSize   int64        `json:"part_size"`
+	TotalParts int          `json:"total_parts"`
+	Parts      []UploadPart `json:"parts"`
+	CreatedAt  time.Time    `json:"created_at"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+}
+
+// UploadPart - часть, принятая S3
+type UploadPart struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+// UploadSessionRequest - начало возобновляемой загрузки
+type UploadSessionRequest struct {
+	FileName    string `json:"file_name" binding:"required"`
+	ContentType string `json:"content_type" binding:"required"`
+	Size        int64  `json:"size" binding:"required"`
+}
+
+// UploadSessionResponse - сессия и части, которые ещё нужно отправить
+type UploadSessionResponse struct {
+	UploadSession
+	Received     int64 `json:"received"`
+	MissingParts []int `json:"missing_parts"`
+}
+
+// PartSizeOf - ожидаемый размер части number (последняя может быть меньше)
+func (s *UploadSession) PartSizeOf(number int) int64 {
+	if number == s.TotalParts {
+		return s.Size - s.PartSize*int64(s.TotalParts-1)
+	}
+	return s.PartSize
+}
+
+// ImageErrorResponse - причина, по которой изображение не принято
+type ImageErrorResponse struct {
+	Error string `json:"error"`
+	// unsupported_format, corrupt_image или image_too_large
+	Code      string   `json:"code" example:"image_too_large"`
+	Format    string   `json:"format,omitempty" example:"png"`
+	Width     int      `json:"width,omitempty"`
+	Height    int      `json:"height,omitempty"`
+	MaxWidth  int      `json:"max_width,omitempty"`
+	MaxHeight int      `json:"max_height,omitempty"`
+	MaxPixels int64    `json:"max_pixels,omitempty"`
+	Supported []string `json:"supported,omitempty"`
+}
@@ -0,0 +1,135 @@
+package primitive
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// computeColor - оптимальный цвет фигуры с заданной прозрачностью,
+// приближающий target поверх current на покрываемых пикселях
+func computeColor(
+	target, current *image.RGBA,
+	lines []Scanline,
+	alpha int,
+) color.NRGBA {
+	var rsum, gsum, bsum, count int64
+	a := 0x101 * 255 / alpha
+
+	for _, line := range lines {
+		i := target.PixOffset(line.X1, line.Y)
+		for x := line.X1; x <= line.X2; x++ {
+			tr := int(target.Pix[i])
+			tg := int(target.Pix[i+1])
+			tb := int(target.Pix[i+2])
+			cr := int(current.Pix[i])
+			cg := int(current.Pix[i+1])
+			cb := int(current.Pix[i+2])
+			i += 4
+
+			rsum += int64((tr-cr)*a + cr*0x101)
+			gsum += int64((tg-cg)*a + cg*0x101)
+			bsum += int64((tb-cb)*a + cb*0x101)
+			count++
+		}
+	}
+
+	if count == 0 {
+		return color.NRGBA{}
+	}
+
+	return color.NRGBA{
+		R: uint8(clampInt(int(rsum/count)>>8, 0, 255)),
+		G: uint8(clampInt(int(gsum/count)>>8, 0, 255)),
+		B: uint8(clampInt(int(bsum/count)>>8, 0, 255)),
+		A: uint8(alpha),
+	}
+}
+
+// drawLines - альфа-композиция цвета поверх изображения по скан-линиям
+func drawLines(im *image.RGBA, c color.NRGBA, lines []Scanline) {
+	const m = 0xffff
+	sr, sg, sb, sa := c.RGBA()
+
+	for _, line := range lines {
+		ma := line.Alpha
+		a := (m - sa*ma/m) * 0x101
+		i := im.PixOffset(line.X1, line.Y)
+		for x := line.X1; x <= line.X2; x++ {
+			dr := uint32(im.Pix[i])
+			dg := uint32(im.Pix[i+1])
+			db := uint32(im.Pix[i+2])
+			da := uint32(im.Pix[i+3])
+			im.Pix[i] = uint8((dr*a + sr*ma) / m >> 8)
+			im.Pix[i+1] = uint8((dg*a + sg*ma) / m >> 8)
+			im.Pix[i+2] = uint8((db*a + sb*ma) / m >> 8)
+			im.Pix[i+3] = uint8((da*a + sa*ma) / m >> 8)
+			i += 4
+		}
+	}
+}
+
+func copyLines(dst, src *image.RGBA, lines []Scanline) {
+	for _, line := range lines {
+		a := dst.PixOffset(line.X1, line.Y)
+		b := a + (line.X2-line.X1+1)*4
+		copy(dst.Pix[a:b], src.Pix[a:b])
+	}
+}
+
+// differenceFull - среднеквадратичное отклонение между изображениями в [0, 1]
+func differenceFull(a, b *image.RGBA) float64 {
+	w, h := a.Bounds().Dx(), a.Bounds().Dy()
+	var total uint64
+
+	for y := 0; y < h; y++ {
+		i := a.PixOffset(0, y)
+		for x := 0; x < w*4; x++ {
+			d := int(a.Pix[i+x]) - int(b.Pix[i+x])
+			total += uint64(d * d)
+		}
+	}
+
+	return math.Sqrt(float64(total)/float64(w*h*4)) / 255
+}
+
+// differencePartial - пересчёт отклонения только по изменившимся пикселям
+func differencePartial(
+	target, before, after *image.RGBA,
+	score float64,
+	lines []Scanline,
+) float64 {
+	w, h := target.Bounds().Dx(), target.Bounds().Dy()
+	total := int64(math.Round(math.Pow(score*255, 2) * float64(w*h*4)))
+
+	for _, line := range lines {
+		i := target.PixOffset(line.X1, line.Y)
+		for x := line.X1; x <= line.X2; x++ {
+			for k := 0; k < 4; k++ {
+				t := int(target.Pix[i+k])
+				d1 := t - int(before.Pix[i+k])
+				d2 := t - int(after.Pix[i+k])
+				total -= int64(d1 * d1)
+				total += int64(d2 * d2)
+			}
+			i += 4
+		}
+	}
+
+	return math.Sqrt(float64(max(total, 0))/float64(w*h*4)) / 255
+}
+
+// energy - качество кандидата: отклонение после его отрисовки
+func energy(
+	shape Shape,
+	alpha int,
+	target, current, buffer *image.RGBA,
+	score float64,
+) float64 {
+	w, h := target.Bounds().Dx(), target.Bounds().Dy()
+	lines := shape.Rasterize(w, h, 1)
+	c := computeColor(target, current, lines, alpha)
+	copyLines(buffer, current, lines)
+	drawLines(buffer, c, lines)
+	return differencePartial(target, current, buffer, score, lines)
+}
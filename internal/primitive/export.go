@@ -0,0 +1,85 @@
+package primitive
+
+import (
+	"fmt"
+	"math"
+)
+
+// Drawing - фигуры модели в порядке добавления; координаты в пикселях
+// результата размером Width x Height
+type Drawing struct {
+	Width      int         `json:"width"`
+	Height     int         `json:"height"`
+	Background string      `json:"background"`
+	Shapes     []ShapeInfo `json:"shapes"`
+}
+
+// ShapeInfo - геометрия и цвет одной фигуры. Заполняются только поля,
+// относящиеся к типу: points у многоугольников и кривых, center и
+// radius у эллипсов, center, size и angle у повёрнутых фигур
+type ShapeInfo struct {
+	Index       int          `json:"index"`
+	Type        string       `json:"type"`
+	Points      [][2]float64 `json:"points,omitempty"`
+	Center      *[2]float64  `json:"center,omitempty"`
+	Radius      *[2]float64  `json:"radius,omitempty"`
+	Size        *[2]float64  `json:"size,omitempty"`
+	Angle       *float64     `json:"angle,omitempty"` // градусы по часовой
+	StrokeWidth *float64     `json:"stroke_width,omitempty"`
+	Color       string       `json:"color"`
+	Alpha       int          `json:"alpha"` // 0-255
+}
+
+// Drawing - описание фигур для результата размером size по большей
+// стороне, в тех же координатах, что Render и SVG
+func (m *Model) Drawing(size int) *Drawing {
+	scale := float64(size) / float64(max(m.Width, m.Height))
+	bg := m.Background
+
+	d := &Drawing{
+		Width:      max(int(float64(m.Width)*scale+0.5), 1),
+		Height:     max(int(float64(m.Height)*scale+0.5), 1),
+		Background: hexColor(bg.R, bg.G, bg.B),
+		Shapes:     make([]ShapeInfo, len(m.Shapes)),
+	}
+	for i, shape := range m.Shapes {
+		c := m.Colors[i]
+		info := shape.Info(scale)
+		info.Index = i
+		info.Color = hexColor(c.R, c.G, c.B)
+		info.Alpha = int(c.A)
+		d.Shapes[i] = info
+	}
+	return d
+}
+
+func hexColor(r, g, b uint8) string {
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+func infoPoints(points []point, scale float64) [][2]float64 {
+	out := make([][2]float64, len(points))
+	for i, p := range points {
+		out[i] = infoPair(p.X*scale, p.Y*scale)
+	}
+	return out
+}
+
+func infoPair(a, b float64) [2]float64 {
+	return [2]float64{infoNum(a), infoNum(b)}
+}
+
+func infoPairPtr(a, b float64) *[2]float64 {
+	pair := infoPair(a, b)
+	return &pair
+}
+
+func infoNumPtr(v float64) *float64 {
+	v = infoNum(v)
+	return &v
+}
+
+// infoNum - точность до сотых пикселя, как в SVG
+func infoNum(v float64) float64 {
+	return math.Round(v*100) / 100
+}
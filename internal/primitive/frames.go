@@ -0,0 +1,48 @@
+package primitive
+
+import (
+	"context"
+	"image"
+	"image/draw"
+)
+
+// Frames - постепенная отрисовка фигур в размере size по большей стороне:
+// frame вызывается для пустого фона, после каждых every фигур и для
+// полного результата. Холст переиспользуется между вызовами, frame не
+// должен сохранять его
+func (m *Model) Frames(
+	ctx context.Context,
+	size, every int,
+	frame func(im *image.RGBA, shapes int) error,
+) error {
+	every = max(every, 1)
+	scale := float64(size) / float64(max(m.Width, m.Height))
+	w := max(int(float64(m.Width)*scale+0.5), 1)
+	h := max(int(float64(m.Height)*scale+0.5), 1)
+
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(
+		out, out.Bounds(),
+		&image.Uniform{C: m.Background}, image.Point{},
+		draw.Src,
+	)
+	if err := frame(out, 0); err != nil {
+		return err
+	}
+
+	for i, shape := range m.Shapes {
+		drawLines(out, m.Colors[i], shape.Rasterize(w, h, scale))
+
+		n := i + 1
+		if n%every != 0 && n != len(m.Shapes) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := frame(out, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package primitive
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Resize - уменьшение до size по большей стороне (меньшие не увеличиваются)
+func Resize(im image.Image, size int) image.Image {
+	b := im.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if size <= 0 || (w <= size && h <= size) {
+		return im
+	}
+
+	if w >= h {
+		h = max(h*size/w, 1)
+		w = size
+	} else {
+		w = max(w*size/h, 1)
+		h = size
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), im, b, xdraw.Src, nil)
+	return dst
+}
+
+// AverageColor - средний цвет изображения
+func AverageColor(im image.Image) color.NRGBA {
+	rgba := toRGBA(im)
+	w, h := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+
+	var r, g, b int
+	for y := 0; y < h; y++ {
+		i := rgba.PixOffset(0, y)
+		for x := 0; x < w; x++ {
+			r += int(rgba.Pix[i])
+			g += int(rgba.Pix[i+1])
+			b += int(rgba.Pix[i+2])
+			i += 4
+		}
+	}
+
+	n := max(w*h, 1)
+	return color.NRGBA{uint8(r / n), uint8(g / n), uint8(b / n), 255}
+}
+
+// ParseBackground - "avg", "white", "black" или hex-цвет (с # или без)
+func ParseBackground(bg string, im image.Image) (color.NRGBA, error) {
+	switch bg {
+	case "", "avg":
+		return AverageColor(im), nil
+	case "white":
+		return color.NRGBA{255, 255, 255, 255}, nil
+	case "black":
+		return color.NRGBA{0, 0, 0, 255}, nil
+	}
+
+	hex := strings.TrimPrefix(bg, "#")
+	if len(hex) != 6 {
+		return color.NRGBA{}, fmt.Errorf("invalid background color %q", bg)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid background color %q", bg)
+	}
+
+	return color.NRGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, nil
+}
+
+func toRGBA(im image.Image) *image.RGBA {
+	b := im.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	xdraw.Draw(dst, dst.Bounds(), im, b.Min, xdraw.Src)
+	return dst
+}
+
+func copyRGBA(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(src.Bounds())
+	copy(dst.Pix, src.Pix)
+	return dst
+}
@@ -0,0 +1,171 @@
+package primitive
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"runtime"
+	"sync"
+)
+
+const (
+	// параметры поиска на один шаг (делятся между воркерами)
+	randomCandidates = 1000
+	hillClimbAge     = 100
+	hillClimbRuns    = 16
+)
+
+// Options - параметры аппроксимации, аналог флагов primitive CLI
+type Options struct {
+	Shape      ShapeType
+	Alpha      int         // 0 - подбирается для каждой фигуры
+	Repeat     int         // дополнительные фигуры того же типа за шаг
+	Background color.NRGBA // фон холста
+	Workers    int         // 0 - все ядра
+	Seed       uint64      // одинаковый seed и Workers дают одинаковый результат
+}
+
+// Model - текущее приближение изображения набором фигур
+type Model struct {
+	Width, Height int
+	Target        *image.RGBA
+	Current       *image.RGBA
+	Background    color.NRGBA
+	Score         float64
+	Shapes        []Shape
+	Colors        []color.NRGBA
+	Scores        []float64
+
+	opts    Options
+	workers []*worker
+}
+
+// New - модель для target; target ожидается уже уменьшенным до рабочего размера
+func New(target image.Image, opts Options) *Model {
+	t := toRGBA(target)
+	bounds := t.Bounds()
+
+	current := image.NewRGBA(bounds)
+	draw.Draw(
+		current, bounds,
+		&image.Uniform{C: opts.Background}, image.Point{},
+		draw.Src,
+	)
+
+	numWorkers := opts.Workers
+	if numWorkers <= 0 {
+		numWorkers = runtime.NumCPU()
+	}
+
+	m := &Model{
+		Width:      bounds.Dx(),
+		Height:     bounds.Dy(),
+		Target:     t,
+		Current:    current,
+		Background: opts.Background,
+		opts:       opts,
+	}
+	m.Score = differenceFull(t, current)
+
+	for i := 0; i < numWorkers; i++ {
+		m.workers = append(m.workers, newWorker(t, opts.Seed, i))
+	}
+
+	return m
+}
+
+// Step - добавляет лучшую найденную фигуру (и Repeat уточнений)
+func (m *Model) Step(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s := m.runWorkers()
+	m.add(s.shape, s.alpha)
+
+	for i := 0; i < m.opts.Repeat; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.worker.init(m.Current, m.Score)
+		s.score = -1
+		before := s.energy()
+		s = hillClimb(s, hillClimbAge)
+		if s.energy() >= before {
+			break
+		}
+		m.add(s.shape, s.alpha)
+	}
+
+	return nil
+}
+
+// Run - выполняет numShapes шагов с проверкой отмены между шагами
+func (m *Model) Run(ctx context.Context, numShapes int) error {
+	for len(m.Shapes) < numShapes {
+		if err := m.Step(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Model) runWorkers() *state {
+	n := max(randomCandidates/len(m.workers), 1)
+	runs := max(hillClimbRuns/len(m.workers), 1)
+
+	results := make([]*state, len(m.workers))
+	var wg sync.WaitGroup
+	for i, wk := range m.workers {
+		wk.init(m.Current, m.Score)
+		wg.Add(1)
+		go func(i int, wk *worker) {
+			defer wg.Done()
+			results[i] = wk.bestHillClimbState(
+				m.opts.Shape, m.opts.Alpha, n, hillClimbAge, runs,
+			)
+		}(i, wk)
+	}
+	wg.Wait()
+
+	best := results[0]
+	for _, s := range results[1:] {
+		if s.energy() < best.energy() {
+			best = s
+		}
+	}
+	return best
+}
+
+func (m *Model) add(shape Shape, alpha int) {
+	before := copyRGBA(m.Current)
+	lines := shape.Rasterize(m.Width, m.Height, 1)
+	c := computeColor(m.Target, m.Current, lines, alpha)
+	drawLines(m.Current, c, lines)
+
+	m.Score = differencePartial(m.Target, before, m.Current, m.Score, lines)
+	m.Shapes = append(m.Shapes, shape)
+	m.Colors = append(m.Colors, c)
+	m.Scores = append(m.Scores, m.Score)
+}
+
+// Render - отрисовка фигур заново в размере size по большей стороне
+func (m *Model) Render(size int) *image.RGBA {
+	scale := float64(size) / float64(max(m.Width, m.Height))
+	w := max(int(float64(m.Width)*scale+0.5), 1)
+	h := max(int(float64(m.Height)*scale+0.5), 1)
+
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(
+		out, out.Bounds(),
+		&image.Uniform{C: m.Background}, image.Point{},
+		draw.Src,
+	)
+
+	for i, shape := range m.Shapes {
+		drawLines(out, m.Colors[i], shape.Rasterize(w, h, scale))
+	}
+
+	return out
+}
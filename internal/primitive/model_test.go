@@ -0,0 +1,117 @@
+package primitive
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// testImage - небольшое синтетическое изображение: градиент с тёмным
+// прямоугольником, чтобы фигурам было что приближать
+func testImage() image.Image {
+	im := image.NewRGBA(image.Rect(0, 0, 32, 24))
+	for y := 0; y < 24; y++ {
+		for x := 0; x < 32; x++ {
+			c := color.RGBA{uint8(x * 8), uint8(y * 10), 128, 255}
+			if x >= 8 && x < 20 && y >= 6 && y < 16 {
+				c = color.RGBA{20, 20, 40, 255}
+			}
+			im.Set(x, y, c)
+		}
+	}
+	return im
+}
+
+func runModel(t *testing.T, shape ShapeType, numShapes int) *Model {
+	t.Helper()
+
+	model := New(testImage(), Options{
+		Shape:      shape,
+		Background: AverageColor(testImage()),
+		Workers:    1,
+		Seed:       42,
+	})
+	if err := model.Run(t.Context(), numShapes); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return model
+}
+
+func TestModelDeterministic(t *testing.T) {
+	first := runModel(t, ShapeTypeAny, 10)
+	second := runModel(t, ShapeTypeAny, 10)
+
+	if len(first.Shapes) != len(second.Shapes) {
+		t.Fatalf("shape count: %d != %d", len(first.Shapes), len(second.Shapes))
+	}
+	for i := range first.Scores {
+		if first.Scores[i] != second.Scores[i] {
+			t.Fatalf("score %d: %v != %v", i, first.Scores[i], second.Scores[i])
+		}
+		if first.Colors[i] != second.Colors[i] {
+			t.Fatalf("color %d: %v != %v", i, first.Colors[i], second.Colors[i])
+		}
+	}
+	if first.SVG(64) != second.SVG(64) {
+		t.Error("SVG output differs between runs")
+	}
+	if !bytes.Equal(first.Render(64).Pix, second.Render(64).Pix) {
+		t.Error("rendered output differs between runs")
+	}
+}
+
+func TestModelSeedChangesResult(t *testing.T) {
+	first := runModel(t, ShapeTypeTriangle, 5)
+
+	model := New(testImage(), Options{
+		Shape:      ShapeTypeTriangle,
+		Background: AverageColor(testImage()),
+		Workers:    1,
+		Seed:       7,
+	})
+	if err := model.Run(t.Context(), 5); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if first.SVG(64) == model.SVG(64) {
+		t.Error("different seeds produced identical output")
+	}
+}
+
+func TestModelScoreImproves(t *testing.T) {
+	model := New(testImage(), Options{
+		Shape:      ShapeTypeAny,
+		Background: AverageColor(testImage()),
+		Workers:    1,
+		Seed:       42,
+	})
+	initial := model.Score
+
+	if err := model.Run(t.Context(), 20); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if model.Score >= initial {
+		t.Fatalf("score did not improve: %v -> %v", initial, model.Score)
+	}
+	prev := initial
+	for i, score := range model.Scores {
+		if score > prev {
+			t.Errorf("shape %d made score worse: %v -> %v", i, prev, score)
+		}
+		prev = score
+	}
+}
+
+func TestModelRunCancelled(t *testing.T) {
+	model := New(testImage(), Options{Workers: 1, Seed: 42})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := model.Run(ctx, 5); err == nil {
+		t.Fatal("Run with cancelled context returned nil error")
+	}
+	if len(model.Shapes) != 0 {
+		t.Fatalf("cancelled run added %d shapes", len(model.Shapes))
+	}
+}
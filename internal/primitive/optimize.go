@@ -0,0 +1,130 @@
+package primitive
+
+import (
+	"image"
+	"math/rand/v2"
+)
+
+// state - кандидат для оптимизации: фигура и её прозрачность
+type state struct {
+	worker      *worker
+	shape       Shape
+	alpha       int
+	mutateAlpha bool
+	score       float64 // < 0 - ещё не посчитан
+}
+
+func (s *state) energy() float64 {
+	if s.score < 0 {
+		s.score = s.worker.energy(s.shape, s.alpha)
+	}
+	return s.score
+}
+
+func (s *state) copy() *state {
+	c := *s
+	c.shape = s.shape.Copy()
+	return &c
+}
+
+// mutate - случайный шаг; возвращает копию для отката
+func (s *state) mutate() *state {
+	old := s.copy()
+	s.shape.Mutate(s.worker.rnd, s.worker.w, s.worker.h)
+	if s.mutateAlpha {
+		s.alpha = clampInt(s.alpha+s.worker.rnd.IntN(21)-10, 1, 255)
+	}
+	s.score = -1
+	return old
+}
+
+// hillClimb - локальный поиск: принимаем только улучшения, останавливаемся
+// после maxAge неудачных попыток подряд
+func hillClimb(s *state, maxAge int) *state {
+	s = s.copy()
+	best := s.copy()
+	bestEnergy := s.energy()
+
+	for age := 0; age < maxAge; age++ {
+		undo := s.mutate()
+		e := s.energy()
+		if e >= bestEnergy {
+			*s = *undo
+			continue
+		}
+		bestEnergy = e
+		best = s.copy()
+		age = -1
+	}
+
+	return best
+}
+
+// worker - независимый поток поиска со своим буфером и генератором
+type worker struct {
+	w, h    int
+	target  *image.RGBA
+	current *image.RGBA
+	buffer  *image.RGBA
+	rnd     *rand.Rand
+	score   float64
+}
+
+func newWorker(target *image.RGBA, seed uint64, index int) *worker {
+	w, h := target.Bounds().Dx(), target.Bounds().Dy()
+	return &worker{
+		w:      w,
+		h:      h,
+		target: target,
+		buffer: image.NewRGBA(target.Bounds()),
+		rnd:    rand.New(rand.NewPCG(seed, uint64(index))),
+	}
+}
+
+func (wk *worker) init(current *image.RGBA, score float64) {
+	wk.current = current
+	wk.score = score
+}
+
+func (wk *worker) energy(shape Shape, alpha int) float64 {
+	return energy(shape, alpha, wk.target, wk.current, wk.buffer, wk.score)
+}
+
+func (wk *worker) randomState(t ShapeType, alpha int) *state {
+	s := &state{
+		worker:      wk,
+		shape:       NewRandomShape(t, wk.rnd, wk.w, wk.h),
+		alpha:       alpha,
+		mutateAlpha: alpha == 0,
+		score:       -1,
+	}
+	if s.mutateAlpha {
+		s.alpha = 128
+	}
+	return s
+}
+
+// bestRandomState - лучший из n случайных кандидатов
+func (wk *worker) bestRandomState(t ShapeType, alpha, n int) *state {
+	var best *state
+	for i := 0; i < n; i++ {
+		s := wk.randomState(t, alpha)
+		if best == nil || s.energy() < best.energy() {
+			best = s
+		}
+	}
+	return best
+}
+
+// bestHillClimbState - m запусков: случайный старт из n кандидатов
+// и локальный поиск с терпением age
+func (wk *worker) bestHillClimbState(t ShapeType, alpha, n, age, m int) *state {
+	var best *state
+	for i := 0; i < m; i++ {
+		s := hillClimb(wk.bestRandomState(t, alpha, n), age)
+		if best == nil || s.energy() < best.energy() {
+			best = s
+		}
+	}
+	return best
+}
@@ -0,0 +1,121 @@
+package primitive
+
+import (
+	"math"
+	"sort"
+)
+
+// Scanline - горизонтальный отрезок пикселей [X1, X2] в строке Y
+type Scanline struct {
+	Y, X1, X2 int
+	Alpha     uint32
+}
+
+type point struct {
+	X, Y float64
+}
+
+type interval struct {
+	x1, x2 int
+}
+
+// rasterizePolygons - заливка набора многоугольников (even-odd внутри
+// каждого, объединение между ними) с выборкой по центрам пикселей
+func rasterizePolygons(w, h int, polys ...[]point) []Scanline {
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, poly := range polys {
+		for _, p := range poly {
+			minY = math.Min(minY, p.Y)
+			maxY = math.Max(maxY, p.Y)
+		}
+	}
+	if math.IsInf(minY, 0) {
+		return nil
+	}
+
+	y1 := clampInt(int(math.Floor(minY)), 0, h-1)
+	y2 := clampInt(int(math.Ceil(maxY)), 0, h-1)
+
+	var lines []Scanline
+	var xs []float64
+	var spans []interval
+
+	for y := y1; y <= y2; y++ {
+		yc := float64(y) + 0.5
+		spans = spans[:0]
+
+		for _, poly := range polys {
+			xs = xs[:0]
+			for i := range poly {
+				a := poly[i]
+				b := poly[(i+1)%len(poly)]
+				if (a.Y <= yc) == (b.Y <= yc) {
+					continue
+				}
+				xs = append(xs, a.X+(yc-a.Y)*(b.X-a.X)/(b.Y-a.Y))
+			}
+			sort.Float64s(xs)
+
+			for i := 0; i+1 < len(xs); i += 2 {
+				x1 := int(math.Ceil(xs[i] - 0.5))
+				x2 := int(math.Ceil(xs[i+1]-0.5)) - 1
+				x1 = max(x1, 0)
+				x2 = min(x2, w-1)
+				if x1 <= x2 {
+					spans = append(spans, interval{x1, x2})
+				}
+			}
+		}
+
+		for _, span := range mergeIntervals(spans) {
+			lines = append(lines, Scanline{
+				Y: y, X1: span.x1, X2: span.x2, Alpha: 0xffff,
+			})
+		}
+	}
+
+	return lines
+}
+
+func mergeIntervals(spans []interval) []interval {
+	if len(spans) < 2 {
+		return spans
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].x1 < spans[j].x1
+	})
+
+	merged := spans[:1]
+	for _, span := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if span.x1 <= last.x2+1 {
+			last.x2 = max(last.x2, span.x2)
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+func scalePoints(points []point, scale float64) []point {
+	scaled := make([]point, len(points))
+	for i, p := range points {
+		scaled[i] = point{p.X * scale, p.Y * scale}
+	}
+	return scaled
+}
+
+func clampInt(x, lo, hi int) int {
+	if x < lo {
+		return lo
+	}
+	if x > hi {
+		return hi
+	}
+	return x
+}
+
+func clamp(x, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, x))
+}
@@ -0,0 +1,663 @@
+package primitive
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+)
+
+// ShapeType - тип фигур, нумерация совпадает с флагом -m у primitive
+type ShapeType int
+
+const (
+	ShapeTypeAny ShapeType = iota
+	ShapeTypeTriangle
+	ShapeTypeRectangle
+	ShapeTypeEllipse
+	ShapeTypeCircle
+	ShapeTypeRotatedRectangle
+	ShapeTypeQuadratic
+	ShapeTypeRotatedEllipse
+	ShapeTypePolygon
+)
+
+// mutateMargin - насколько фигура может выходить за границы изображения
+const mutateMargin = 16
+
+type Shape interface {
+	// Rasterize - скан-линии фигуры на холсте w x h с масштабом scale
+	Rasterize(w, h int, scale float64) []Scanline
+	// SVG - элемент SVG в координатах модели; attrs - атрибуты заливки
+	SVG(attrs string) string
+	// Info - геометрия фигуры для описания рисунка в масштабе scale
+	Info(scale float64) ShapeInfo
+	Copy() Shape
+	Mutate(rnd *rand.Rand, w, h int)
+}
+
+func NewRandomShape(t ShapeType, rnd *rand.Rand, w, h int) Shape {
+	if t == ShapeTypeAny {
+		t = ShapeType(rnd.IntN(int(ShapeTypePolygon)) + 1)
+	}
+
+	switch t {
+	case ShapeTypeTriangle:
+		return NewRandomTriangle(rnd, w, h)
+	case ShapeTypeRectangle:
+		return NewRandomRectangle(rnd, w, h)
+	case ShapeTypeEllipse:
+		return NewRandomEllipse(rnd, w, h)
+	case ShapeTypeCircle:
+		return NewRandomCircle(rnd, w, h)
+	case ShapeTypeRotatedRectangle:
+		return NewRandomRotatedRectangle(rnd, w, h)
+	case ShapeTypeQuadratic:
+		return NewRandomQuadratic(rnd, w, h)
+	case ShapeTypeRotatedEllipse:
+		return NewRandomRotatedEllipse(rnd, w, h)
+	case ShapeTypePolygon:
+		return NewRandomPolygon(rnd, w, h)
+	default:
+		panic(fmt.Sprintf("primitive: unknown shape type %d", t))
+	}
+}
+
+// Triangle
+
+type Triangle struct {
+	Points [3]point
+}
+
+func NewRandomTriangle(rnd *rand.Rand, w, h int) *Triangle {
+	x := rnd.Float64() * float64(w)
+	y := rnd.Float64() * float64(h)
+
+	t := &Triangle{}
+	for i := range t.Points {
+		t.Points[i] = point{
+			x + rnd.Float64()*31 - 15,
+			y + rnd.Float64()*31 - 15,
+		}
+	}
+	t.Mutate(rnd, w, h)
+	return t
+}
+
+func (t *Triangle) Rasterize(w, h int, scale float64) []Scanline {
+	return rasterizePolygons(w, h, scalePoints(t.Points[:], scale))
+}
+
+func (t *Triangle) SVG(attrs string) string {
+	return fmt.Sprintf(
+		`<polygon %s points="%s" />`,
+		attrs,
+		svgPoints(t.Points[:]),
+	)
+}
+
+func (t *Triangle) Info(scale float64) ShapeInfo {
+	return ShapeInfo{
+		Type:   "triangle",
+		Points: infoPoints(t.Points[:], scale),
+	}
+}
+
+func (t *Triangle) Copy() Shape {
+	c := *t
+	return &c
+}
+
+func (t *Triangle) Mutate(rnd *rand.Rand, w, h int) {
+	for {
+		i := rnd.IntN(3)
+		t.Points[i] = movePoint(rnd, t.Points[i], w, h)
+		if t.valid() {
+			return
+		}
+	}
+}
+
+// valid - отсекаем слишком вытянутые треугольники (угол меньше 15°)
+func (t *Triangle) valid() bool {
+	const minDegrees = 15
+	for i := range t.Points {
+		a := t.Points[i]
+		b := t.Points[(i+1)%3]
+		c := t.Points[(i+2)%3]
+		if angle(a, b, c) < minDegrees {
+			return false
+		}
+	}
+	return true
+}
+
+// Rectangle
+
+type Rectangle struct {
+	X1, Y1, X2, Y2 float64
+}
+
+func NewRandomRectangle(rnd *rand.Rand, w, h int) *Rectangle {
+	x := rnd.Float64() * float64(w)
+	y := rnd.Float64() * float64(h)
+	r := &Rectangle{
+		X1: x,
+		Y1: y,
+		X2: clamp(x+rnd.Float64()*32+1, 0, float64(w)),
+		Y2: clamp(y+rnd.Float64()*32+1, 0, float64(h)),
+	}
+	return r
+}
+
+func (r *Rectangle) bounds() (x1, y1, x2, y2 float64) {
+	return math.Min(r.X1, r.X2), math.Min(r.Y1, r.Y2),
+		math.Max(r.X1, r.X2), math.Max(r.Y1, r.Y2)
+}
+
+func (r *Rectangle) Rasterize(w, h int, scale float64) []Scanline {
+	x1, y1, x2, y2 := r.bounds()
+	return rasterizePolygons(w, h, scalePoints([]point{
+		{x1, y1}, {x2, y1}, {x2, y2}, {x1, y2},
+	}, scale))
+}
+
+func (r *Rectangle) SVG(attrs string) string {
+	x1, y1, x2, y2 := r.bounds()
+	return fmt.Sprintf(
+		`<rect %s x="%s" y="%s" width="%s" height="%s" />`,
+		attrs, svgNum(x1), svgNum(y1), svgNum(x2-x1), svgNum(y2-y1),
+	)
+}
+
+func (r *Rectangle) Info(scale float64) ShapeInfo {
+	x1, y1, x2, y2 := r.bounds()
+	return ShapeInfo{
+		Type: "rectangle",
+		Points: infoPoints([]point{
+			{x1, y1}, {x2, y1}, {x2, y2}, {x1, y2},
+		}, scale),
+	}
+}
+
+func (r *Rectangle) Copy() Shape {
+	c := *r
+	return &c
+}
+
+func (r *Rectangle) Mutate(rnd *rand.Rand, w, h int) {
+	switch rnd.IntN(2) {
+	case 0:
+		r.X1 = clamp(r.X1+rnd.NormFloat64()*16, 0, float64(w))
+		r.Y1 = clamp(r.Y1+rnd.NormFloat64()*16, 0, float64(h))
+	case 1:
+		r.X2 = clamp(r.X2+rnd.NormFloat64()*16, 0, float64(w))
+		r.Y2 = clamp(r.Y2+rnd.NormFloat64()*16, 0, float64(h))
+	}
+}
+
+// Ellipse
+
+type Ellipse struct {
+	X, Y   float64
+	Rx, Ry float64
+	Circle bool
+}
+
+func NewRandomEllipse(rnd *rand.Rand, w, h int) *Ellipse {
+	return &Ellipse{
+		X:  rnd.Float64() * float64(w),
+		Y:  rnd.Float64() * float64(h),
+		Rx: rnd.Float64()*32 + 1,
+		Ry: rnd.Float64()*32 + 1,
+	}
+}
+
+func NewRandomCircle(rnd *rand.Rand, w, h int) *Ellipse {
+	r := rnd.Float64()*32 + 1
+	return &Ellipse{
+		X:      rnd.Float64() * float64(w),
+		Y:      rnd.Float64() * float64(h),
+		Rx:     r,
+		Ry:     r,
+		Circle: true,
+	}
+}
+
+func (e *Ellipse) Rasterize(w, h int, scale float64) []Scanline {
+	cx, cy := e.X*scale, e.Y*scale
+	rx, ry := e.Rx*scale, e.Ry*scale
+
+	y1 := clampInt(int(math.Floor(cy-ry)), 0, h-1)
+	y2 := clampInt(int(math.Ceil(cy+ry)), 0, h-1)
+
+	var lines []Scanline
+	for y := y1; y <= y2; y++ {
+		dy := (float64(y) + 0.5 - cy) / ry
+		if dy*dy >= 1 {
+			continue
+		}
+		half := rx * math.Sqrt(1-dy*dy)
+		x1 := max(int(math.Ceil(cx-half-0.5)), 0)
+		x2 := min(int(math.Ceil(cx+half-0.5))-1, w-1)
+		if x1 <= x2 {
+			lines = append(lines, Scanline{Y: y, X1: x1, X2: x2, Alpha: 0xffff})
+		}
+	}
+	return lines
+}
+
+func (e *Ellipse) SVG(attrs string) string {
+	return fmt.Sprintf(
+		`<ellipse %s cx="%s" cy="%s" rx="%s" ry="%s" />`,
+		attrs, svgNum(e.X), svgNum(e.Y), svgNum(e.Rx), svgNum(e.Ry),
+	)
+}
+
+func (e *Ellipse) Info(scale float64) ShapeInfo {
+	shapeType := "ellipse"
+	if e.Circle {
+		shapeType = "circle"
+	}
+	return ShapeInfo{
+		Type:   shapeType,
+		Center: infoPairPtr(e.X*scale, e.Y*scale),
+		Radius: infoPairPtr(e.Rx*scale, e.Ry*scale),
+	}
+}
+
+func (e *Ellipse) Copy() Shape {
+	c := *e
+	return &c
+}
+
+func (e *Ellipse) Mutate(rnd *rand.Rand, w, h int) {
+	switch rnd.IntN(3) {
+	case 0:
+		p := movePoint(rnd, point{e.X, e.Y}, w, h)
+		e.X, e.Y = p.X, p.Y
+	case 1:
+		e.Rx = clamp(e.Rx+rnd.NormFloat64()*16, 1, float64(w))
+		if e.Circle {
+			e.Ry = e.Rx
+		}
+	case 2:
+		e.Ry = clamp(e.Ry+rnd.NormFloat64()*16, 1, float64(h))
+		if e.Circle {
+			e.Rx = e.Ry
+		}
+	}
+}
+
+// RotatedRectangle
+
+type RotatedRectangle struct {
+	X, Y   float64
+	Sx, Sy float64
+	Angle  float64 // градусы
+}
+
+func NewRandomRotatedRectangle(
+	rnd *rand.Rand,
+	w, h int,
+) *RotatedRectangle {
+	r := &RotatedRectangle{
+		X:     rnd.Float64() * float64(w),
+		Y:     rnd.Float64() * float64(h),
+		Sx:    rnd.Float64()*32 + 1,
+		Sy:    rnd.Float64()*32 + 1,
+		Angle: rnd.Float64() * 360,
+	}
+	r.Mutate(rnd, w, h)
+	return r
+}
+
+func (r *RotatedRectangle) corners() []point {
+	sin, cos := math.Sincos(r.Angle * math.Pi / 180)
+	hx, hy := r.Sx/2, r.Sy/2
+
+	corners := []point{{-hx, -hy}, {hx, -hy}, {hx, hy}, {-hx, hy}}
+	for i, c := range corners {
+		corners[i] = point{
+			r.X + c.X*cos - c.Y*sin,
+			r.Y + c.X*sin + c.Y*cos,
+		}
+	}
+	return corners
+}
+
+func (r *RotatedRectangle) Rasterize(w, h int, scale float64) []Scanline {
+	return rasterizePolygons(w, h, scalePoints(r.corners(), scale))
+}
+
+func (r *RotatedRectangle) SVG(attrs string) string {
+	return fmt.Sprintf(
+		`<polygon %s points="%s" />`,
+		attrs,
+		svgPoints(r.corners()),
+	)
+}
+
+func (r *RotatedRectangle) Info(scale float64) ShapeInfo {
+	return ShapeInfo{
+		Type:   "rotated_rectangle",
+		Points: infoPoints(r.corners(), scale),
+		Center: infoPairPtr(r.X*scale, r.Y*scale),
+		Size:   infoPairPtr(r.Sx*scale, r.Sy*scale),
+		Angle:  infoNumPtr(r.Angle),
+	}
+}
+
+func (r *RotatedRectangle) Copy() Shape {
+	c := *r
+	return &c
+}
+
+func (r *RotatedRectangle) Mutate(rnd *rand.Rand, w, h int) {
+	for {
+		switch rnd.IntN(3) {
+		case 0:
+			p := movePoint(rnd, point{r.X, r.Y}, w, h)
+			r.X, r.Y = p.X, p.Y
+		case 1:
+			r.Sx = clamp(r.Sx+rnd.NormFloat64()*16, 1, float64(w))
+			r.Sy = clamp(r.Sy+rnd.NormFloat64()*16, 1, float64(h))
+		case 2:
+			r.Angle += rnd.NormFloat64() * 32
+		}
+		if r.valid() {
+			return
+		}
+	}
+}
+
+func (r *RotatedRectangle) valid() bool {
+	a, b := r.Sx, r.Sy
+	if a < b {
+		a, b = b, a
+	}
+	return a/b <= 5
+}
+
+// RotatedEllipse
+
+type RotatedEllipse struct {
+	X, Y   float64
+	Rx, Ry float64
+	Angle  float64 // градусы
+}
+
+// rotatedEllipseSegments - точность аппроксимации эллипса многоугольником
+const rotatedEllipseSegments = 32
+
+func NewRandomRotatedEllipse(
+	rnd *rand.Rand,
+	w, h int,
+) *RotatedEllipse {
+	return &RotatedEllipse{
+		X:     rnd.Float64() * float64(w),
+		Y:     rnd.Float64() * float64(h),
+		Rx:    rnd.Float64()*32 + 1,
+		Ry:    rnd.Float64()*32 + 1,
+		Angle: rnd.Float64() * 360,
+	}
+}
+
+func (e *RotatedEllipse) outline() []point {
+	sin, cos := math.Sincos(e.Angle * math.Pi / 180)
+	points := make([]point, rotatedEllipseSegments)
+	for i := range points {
+		t := 2 * math.Pi * float64(i) / rotatedEllipseSegments
+		x := e.Rx * math.Cos(t)
+		y := e.Ry * math.Sin(t)
+		points[i] = point{e.X + x*cos - y*sin, e.Y + x*sin + y*cos}
+	}
+	return points
+}
+
+func (e *RotatedEllipse) Rasterize(w, h int, scale float64) []Scanline {
+	return rasterizePolygons(w, h, scalePoints(e.outline(), scale))
+}
+
+func (e *RotatedEllipse) SVG(attrs string) string {
+	return fmt.Sprintf(
+		`<ellipse %s transform="translate(%s %s) rotate(%s)" rx="%s" ry="%s" />`,
+		attrs,
+		svgNum(e.X), svgNum(e.Y), svgNum(e.Angle),
+		svgNum(e.Rx), svgNum(e.Ry),
+	)
+}
+
+func (e *RotatedEllipse) Info(scale float64) ShapeInfo {
+	return ShapeInfo{
+		Type:   "rotated_ellipse",
+		Center: infoPairPtr(e.X*scale, e.Y*scale),
+		Radius: infoPairPtr(e.Rx*scale, e.Ry*scale),
+		Angle:  infoNumPtr(e.Angle),
+	}
+}
+
+func (e *RotatedEllipse) Copy() Shape {
+	c := *e
+	return &c
+}
+
+func (e *RotatedEllipse) Mutate(rnd *rand.Rand, w, h int) {
+	switch rnd.IntN(3) {
+	case 0:
+		p := movePoint(rnd, point{e.X, e.Y}, w, h)
+		e.X, e.Y = p.X, p.Y
+	case 1:
+		e.Rx = clamp(e.Rx+rnd.NormFloat64()*16, 1, float64(w))
+		e.Ry = clamp(e.Ry+rnd.NormFloat64()*16, 1, float64(h))
+	case 2:
+		e.Angle += rnd.NormFloat64() * 32
+	}
+}
+
+// Polygon - произвольный четырёхугольник (может быть невыпуклым)
+
+type Polygon struct {
+	Points []point
+}
+
+const polygonOrder = 4
+
+func NewRandomPolygon(rnd *rand.Rand, w, h int) *Polygon {
+	x := rnd.Float64() * float64(w)
+	y := rnd.Float64() * float64(h)
+
+	p := &Polygon{Points: make([]point, polygonOrder)}
+	for i := range p.Points {
+		p.Points[i] = point{
+			x + rnd.Float64()*40 - 20,
+			y + rnd.Float64()*40 - 20,
+		}
+	}
+	p.Mutate(rnd, w, h)
+	return p
+}
+
+func (p *Polygon) Rasterize(w, h int, scale float64) []Scanline {
+	return rasterizePolygons(w, h, scalePoints(p.Points, scale))
+}
+
+func (p *Polygon) SVG(attrs string) string {
+	return fmt.Sprintf(
+		`<polygon %s points="%s" />`,
+		attrs,
+		svgPoints(p.Points),
+	)
+}
+
+func (p *Polygon) Info(scale float64) ShapeInfo {
+	return ShapeInfo{
+		Type:   "polygon",
+		Points: infoPoints(p.Points, scale),
+	}
+}
+
+func (p *Polygon) Copy() Shape {
+	c := &Polygon{Points: make([]point, len(p.Points))}
+	copy(c.Points, p.Points)
+	return c
+}
+
+func (p *Polygon) Mutate(rnd *rand.Rand, w, h int) {
+	if rnd.Float64() < 0.25 {
+		i := rnd.IntN(len(p.Points))
+		j := rnd.IntN(len(p.Points))
+		p.Points[i], p.Points[j] = p.Points[j], p.Points[i]
+		return
+	}
+
+	i := rnd.IntN(len(p.Points))
+	p.Points[i] = movePoint(rnd, p.Points[i], w, h)
+}
+
+// Quadratic - квадратичная кривая Безье, рисуется обводкой
+
+type Quadratic struct {
+	Points [3]point
+	Width  float64
+}
+
+// quadraticSegments - количество отрезков при разбиении кривой
+const quadraticSegments = 16
+
+func NewRandomQuadratic(rnd *rand.Rand, w, h int) *Quadratic {
+	x := rnd.Float64() * float64(w)
+	y := rnd.Float64() * float64(h)
+
+	q := &Quadratic{Width: 1 + rnd.Float64()*2}
+	for i := range q.Points {
+		q.Points[i] = point{
+			x + rnd.Float64()*40 - 20,
+			y + rnd.Float64()*40 - 20,
+		}
+	}
+	q.Mutate(rnd, w, h)
+	return q
+}
+
+func (q *Quadratic) Rasterize(w, h int, scale float64) []Scanline {
+	p0, p1, p2 := q.Points[0], q.Points[1], q.Points[2]
+	half := q.Width * scale / 2
+
+	var prev point
+	polys := make([][]point, 0, quadraticSegments)
+	for i := 0; i <= quadraticSegments; i++ {
+		t := float64(i) / quadraticSegments
+		u := 1 - t
+		cur := point{
+			(u*u*p0.X + 2*u*t*p1.X + t*t*p2.X) * scale,
+			(u*u*p0.Y + 2*u*t*p1.Y + t*t*p2.Y) * scale,
+		}
+		if i > 0 {
+			if seg := strokeSegment(prev, cur, half); seg != nil {
+				polys = append(polys, seg)
+			}
+		}
+		prev = cur
+	}
+
+	return rasterizePolygons(w, h, polys...)
+}
+
+// SVG - кривая рисуется обводкой, поэтому атрибуты заливки становятся
+// атрибутами обводки; концы квадратные, как при растеризации
+func (q *Quadratic) SVG(attrs string) string {
+	p0, p1, p2 := q.Points[0], q.Points[1], q.Points[2]
+	return fmt.Sprintf(
+		`<path %s fill="none" stroke-width="%s" stroke-linecap="square" `+
+			`d="M %s %s Q %s %s %s %s" />`,
+		strings.ReplaceAll(attrs, "fill", "stroke"),
+		svgNum(q.Width),
+		svgNum(p0.X), svgNum(p0.Y),
+		svgNum(p1.X), svgNum(p1.Y),
+		svgNum(p2.X), svgNum(p2.Y),
+	)
+}
+
+// Info - начальная точка, контрольная точка и конечная точка кривой
+func (q *Quadratic) Info(scale float64) ShapeInfo {
+	return ShapeInfo{
+		Type:        "quadratic",
+		Points:      infoPoints(q.Points[:], scale),
+		StrokeWidth: infoNumPtr(q.Width * scale),
+	}
+}
+
+func (q *Quadratic) Copy() Shape {
+	c := *q
+	return &c
+}
+
+func (q *Quadratic) Mutate(rnd *rand.Rand, w, h int) {
+	for {
+		switch rnd.IntN(4) {
+		case 3:
+			q.Width = clamp(q.Width+rnd.NormFloat64(), 1, 16)
+		default:
+			i := rnd.IntN(3)
+			q.Points[i] = movePoint(rnd, q.Points[i], w, h)
+		}
+		if q.valid() {
+			return
+		}
+	}
+}
+
+// valid - кривая не должна вырождаться в точку или петлю
+func (q *Quadratic) valid() bool {
+	d01 := distance(q.Points[0], q.Points[1])
+	d12 := distance(q.Points[1], q.Points[2])
+	d02 := distance(q.Points[0], q.Points[2])
+	if d02 < 1 {
+		return false
+	}
+	return d01+d12 < 2*d02 && angle(q.Points[1], q.Points[0], q.Points[2]) > 30
+}
+
+// strokeSegment - прямоугольник толщиной 2*half вдоль отрезка,
+// удлинённый на half с концов, чтобы закрыть стыки
+func strokeSegment(a, b point, half float64) []point {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return nil
+	}
+	ux, uy := dx/length*half, dy/length*half
+	nx, ny := -uy, ux
+
+	return []point{
+		{a.X - ux + nx, a.Y - uy + ny},
+		{b.X + ux + nx, b.Y + uy + ny},
+		{b.X + ux - nx, b.Y + uy - ny},
+		{a.X - ux - nx, a.Y - uy - ny},
+	}
+}
+
+func movePoint(rnd *rand.Rand, p point, w, h int) point {
+	return point{
+		clamp(p.X+rnd.NormFloat64()*16, -mutateMargin, float64(w+mutateMargin)),
+		clamp(p.Y+rnd.NormFloat64()*16, -mutateMargin, float64(h+mutateMargin)),
+	}
+}
+
+func distance(a, b point) float64 {
+	return math.Hypot(b.X-a.X, b.Y-a.Y)
+}
+
+// angle - угол при вершине a между лучами ab и ac, в градусах
+func angle(a, b, c point) float64 {
+	x1, y1 := b.X-a.X, b.Y-a.Y
+	x2, y2 := c.X-a.X, c.Y-a.Y
+	d1 := math.Hypot(x1, y1)
+	d2 := math.Hypot(x2, y2)
+	if d1 == 0 || d2 == 0 {
+		return 0
+	}
+	cos := clamp((x1*x2+y1*y2)/(d1*d2), -1, 1)
+	return math.Acos(cos) * 180 / math.Pi
+}
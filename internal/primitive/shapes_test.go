@@ -0,0 +1,76 @@
+package primitive
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+var shapeTypes = []struct {
+	name  string
+	shape ShapeType
+}{
+	{"triangle", ShapeTypeTriangle},
+	{"rectangle", ShapeTypeRectangle},
+	{"ellipse", ShapeTypeEllipse},
+	{"circle", ShapeTypeCircle},
+	{"rotated_rectangle", ShapeTypeRotatedRectangle},
+	{"quadratic", ShapeTypeQuadratic},
+	{"rotated_ellipse", ShapeTypeRotatedEllipse},
+	{"polygon", ShapeTypePolygon},
+}
+
+// checkLines - скан-линии внутри холста w x h и не вырождены
+func checkLines(t *testing.T, lines []Scanline, w, h int) {
+	t.Helper()
+	for _, line := range lines {
+		if line.Y < 0 || line.Y >= h {
+			t.Fatalf("scanline y=%d outside 0..%d", line.Y, h-1)
+		}
+		if line.X1 < 0 || line.X2 >= w || line.X1 > line.X2 {
+			t.Fatalf(
+				"scanline y=%d x=[%d, %d] outside 0..%d",
+				line.Y, line.X1, line.X2, w-1,
+			)
+		}
+	}
+}
+
+func TestRasterizeBounds(t *testing.T) {
+	const w, h = 40, 30
+
+	for _, tt := range shapeTypes {
+		t.Run(tt.name, func(t *testing.T) {
+			rnd := rand.New(rand.NewPCG(1, 2))
+			for i := 0; i < 200; i++ {
+				shape := NewRandomShape(tt.shape, rnd, w, h)
+				// мутации уводят фигуру за край на mutateMargin
+				for j := 0; j < i%20; j++ {
+					shape.Mutate(rnd, w, h)
+				}
+
+				checkLines(t, shape.Rasterize(w, h, 1), w, h)
+				// в выходном размере холст больше модели
+				checkLines(t, shape.Rasterize(w*5/2, h*5/2, 2.5), w*5/2, h*5/2)
+			}
+		})
+	}
+}
+
+func TestRasterizeNotEmpty(t *testing.T) {
+	const w, h = 40, 30
+
+	for _, tt := range shapeTypes {
+		t.Run(tt.name, func(t *testing.T) {
+			rnd := rand.New(rand.NewPCG(3, 4))
+			covered := 0
+			for i := 0; i < 50; i++ {
+				if len(NewRandomShape(tt.shape, rnd, w, h).Rasterize(w, h, 1)) > 0 {
+					covered++
+				}
+			}
+			if covered == 0 {
+				t.Fatal("no random shape covered any pixel")
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package primitive
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SVG - фигуры модели векторным изображением размером size по большей
+// стороне; фигуры хранятся в координатах рабочего размера и
+// масштабируются общим transform
+func (m *Model) SVG(size int) string {
+	scale := float64(size) / float64(max(m.Width, m.Height))
+	w := max(int(float64(m.Width)*scale+0.5), 1)
+	h := max(int(float64(m.Height)*scale+0.5), 1)
+	bg := m.Background
+
+	var b strings.Builder
+	fmt.Fprintf(&b,
+		`<svg xmlns="http://www.w3.org/2000/svg" version="1.1" `+
+			`width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		w, h, w, h)
+	fmt.Fprintf(&b,
+		`<rect width="%d" height="%d" fill="#%02x%02x%02x" />`+"\n",
+		w, h, bg.R, bg.G, bg.B)
+	fmt.Fprintf(&b, `<g transform="scale(%s)">`+"\n", svgNum(scale))
+
+	for i, shape := range m.Shapes {
+		c := m.Colors[i]
+		// id совпадает с index в описании фигур (Drawing)
+		attrs := fmt.Sprintf(
+			`id="shape-%d" fill="#%02x%02x%02x" fill-opacity="%s"`,
+			i, c.R, c.G, c.B, svgNum(float64(c.A)/255),
+		)
+		b.WriteString(shape.SVG(attrs))
+		b.WriteByte('\n')
+	}
+
+	b.WriteString("</g>\n</svg>\n")
+	return b.String()
+}
+
+// svgNum - координата с точностью до сотых без лишних нулей
+func svgNum(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+func svgPoints(points []point) string {
+	parts := make([]string, len(points))
+	for i, p := range points {
+		parts[i] = svgNum(p.X) + "," + svgNum(p.Y)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,109 @@
+package processors
+
+import (
+	"fmt"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
+)
+
+// outputParams - параметры формата результата, общие для эффектов
+func outputParams(formats []string) []models.EffectParam {
+	defaults := ut.NewOutputConfig()
+
+	return []models.EffectParam{
+		{
+			Name:        "output_format",
+			Type:        "string",
+			Description: "Output encoding, webp is lossless",
+			Default:     string(defaults.Format),
+			Enum:        formats,
+		},
+		{
+			Name:        "quality",
+			Type:        "int",
+			Description: "JPEG quality, ignored by other formats",
+			Default:     defaults.Quality,
+			Min:         bound(ut.MinQuality),
+			Max:         bound(ut.MaxQuality),
+		},
+	}
+}
+
+// rasterFormats - форматы для эффектов без фигур (все, кроме svg)
+func rasterFormats() []string {
+	var formats []string
+	for _, format := range ut.OutputFormats {
+		if ut.OutputFormat(format) != ut.FormatSVG {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+// shapeParams - параметры результата, доступные только эффектам из фигур
+func shapeParams() []models.EffectParam {
+	return []models.EffectParam{
+		{
+			Name: "shapes_json",
+			Type: "bool",
+			Description: "Also store shapes.json with every shape " +
+				"(type, coordinates, color, alpha) in placement order",
+			Default: false,
+		},
+		{
+			Name: "animation",
+			Type: "string",
+			Description: "Also store the drawing built up shape by shape: " +
+				"animated GIF or ZIP of PNG frames",
+			Enum: ut.Animations,
+		},
+		{
+			Name: "frame_interval",
+			Type: "int",
+			Description: fmt.Sprintf(
+				"Shapes added between animation frames, "+
+					"default gives about 50 frames, at most %d frames",
+				ut.MaxAnimationFrames,
+			),
+			Min: bound(ut.MinFrameInterval),
+			Max: bound(ut.MaxFrameInterval),
+		},
+		{
+			Name:        "frame_delay",
+			Type:        "int",
+			Description: "Delay between GIF frames in milliseconds",
+			Default:     ut.DefaultFrameDelay,
+			Min:         bound(ut.MinFrameDelay),
+			Max:         bound(ut.MaxFrameDelay),
+		},
+	}
+}
+
+// checkRasterOutput - SVG, описание фигур и анимация строятся из фигур,
+// растровым эффектам они недоступны
+func checkRasterOutput(effect string, params models.ProcessingParams) error {
+	format, _ := ut.ParseOutputFormat(params.OutputFormat)
+	if format == ut.FormatSVG {
+		return fmt.Errorf(
+			"%w: output_format svg is not supported by effect %q",
+			ut.ErrInvalidParams,
+			effect,
+		)
+	}
+	if params.ShapesJSON != nil && *params.ShapesJSON {
+		return fmt.Errorf(
+			"%w: shapes_json is not supported by effect %q",
+			ut.ErrInvalidParams,
+			effect,
+		)
+	}
+	if params.Animation != "" {
+		return fmt.Errorf(
+			"%w: animation is not supported by effect %q",
+			ut.ErrInvalidParams,
+			effect,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package processors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
+)
+
+type PencilProcessor struct{}
+
+func NewPencilProcessor() *PencilProcessor {
+	return &PencilProcessor{}
+}
+
+func (p *PencilProcessor) Name() string {
+	return string(ut.EffectPencil)
+}
+
+func (p *PencilProcessor) Description() string {
+	return "Grayscale pencil sketch with optional paper texture and hatching"
+}
+
+func (p *PencilProcessor) Params() []models.EffectParam {
+	defaults := ut.NewPencilConfig()
+
+	return append([]models.EffectParam{
+		{
+			Name:        "style",
+			Type:        "string",
+			Description: "Preset applied before explicit overrides",
+			Enum:        pencilStyles(),
+		},
+		{
+			Name:        "blur_sigma",
+			Type:        "number",
+			Description: "Gaussian blur strength, larger means softer lines",
+			Default:     defaults.BlurSigma,
+			Min:         bound(ut.MinBlurSigma),
+			Max:         bound(ut.MaxBlurSigma),
+		},
+		{
+			Name:        "paper_texture",
+			Type:        "bool",
+			Description: "Overlay paper grain",
+			Default:     defaults.PaperTexture,
+		},
+		{
+			Name:        "hatching",
+			Type:        "bool",
+			Description: "Hatch dark areas with diagonal strokes",
+			Default:     defaults.Hatching,
+		},
+		{
+			Name:        "output_size",
+			Type:        "int",
+			Description: "Largest side of the output image in pixels",
+			Default:     ut.NewImageProcessor().Config.OutputSize,
+			Min:         bound(ut.MinOutputSize),
+			Max:         bound(ut.MaxOutputSize),
+		},
+	}, outputParams(rasterFormats())...)
+}
+
+func (p *PencilProcessor) Validate(params models.ProcessingParams) error {
+	if params.Style != "" &&
+		ut.EffectForStyle(params.Style) != ut.EffectPencil {
+		return fmt.Errorf(
+			"%w: style %q is not supported by effect %q",
+			ut.ErrInvalidParams,
+			params.Style,
+			p.Name(),
+		)
+	}
+	if err := checkRasterOutput(p.Name(), params); err != nil {
+		return err
+	}
+	return ut.ValidateProcessingParams(params)
+}
+
+func (p *PencilProcessor) Process(
+	ctx context.Context,
+	input []byte,
+	params models.ProcessingParams,
+) (*Result, error) {
+	if params.Style == "" {
+		params.Style = "pencil"
+	}
+
+	imageProcessor, err := ut.NewImageProcessorFromParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := imageProcessor.CreatePencilSketch(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Data:     output,
+		MimeType: imageProcessor.Output.Format.MimeType(),
+	}, nil
+}
+
+func pencilStyles() []string {
+	var styles []string
+	for _, style := range ut.Styles {
+		if ut.EffectForStyle(style) == ut.EffectPencil {
+			styles = append(styles, style)
+		}
+	}
+	return styles
+}
@@ -0,0 +1,123 @@
+package processors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
+)
+
+type PrimitiveProcessor struct{}
+
+func NewPrimitiveProcessor() *PrimitiveProcessor {
+	return &PrimitiveProcessor{}
+}
+
+func (p *PrimitiveProcessor) Name() string {
+	return string(ut.EffectPrimitive)
+}
+
+func (p *PrimitiveProcessor) Description() string {
+	return "Approximates the image with geometric shapes"
+}
+
+func (p *PrimitiveProcessor) Params() []models.EffectParam {
+	defaults := ut.NewImageProcessor().Config
+
+	return append([]models.EffectParam{
+		{
+			Name:        "style",
+			Type:        "string",
+			Description: "Preset applied before explicit overrides",
+			Enum:        primitiveStyles(),
+		},
+		{
+			Name:        "num_shapes",
+			Type:        "int",
+			Description: "Number of shapes",
+			Default:     defaults.NumShapes,
+			Min:         bound(ut.MinNumShapes),
+			Max:         bound(ut.MaxNumShapes),
+		},
+		{
+			Name: "mode",
+			Type: "int",
+			Description: "Shape type: 0=any, 1=triangle, 2=rectangle, " +
+				"3=ellipse, 4=circle, 5=rotated rectangle, 6=bezier, " +
+				"7=rotated ellipse, 8=polygon",
+			Default: defaults.Mode,
+			Min:     bound(ut.MinMode),
+			Max:     bound(ut.MaxMode),
+		},
+		{
+			Name:        "alpha",
+			Type:        "int",
+			Description: "Shape opacity, 0 picks it per shape",
+			Default:     defaults.Alpha,
+			Min:         bound(ut.MinAlpha),
+			Max:         bound(ut.MaxAlpha),
+		},
+		{
+			Name:        "background",
+			Type:        "string",
+			Description: "avg, white, black or hex color",
+			Default:     defaults.Background,
+		},
+		{
+			Name:        "output_size",
+			Type:        "int",
+			Description: "Largest side of the output image in pixels",
+			Default:     defaults.OutputSize,
+			Min:         bound(ut.MinOutputSize),
+			Max:         bound(ut.MaxOutputSize),
+		},
+	}, append(outputParams(ut.OutputFormats), shapeParams()...)...)
+}
+
+func (p *PrimitiveProcessor) Validate(params models.ProcessingParams) error {
+	if ut.EffectForStyle(params.Style) != ut.EffectPrimitive {
+		return fmt.Errorf(
+			"%w: style %q is not supported by effect %q",
+			ut.ErrInvalidParams,
+			params.Style,
+			p.Name(),
+		)
+	}
+	return ut.ValidateProcessingParams(params)
+}
+
+func (p *PrimitiveProcessor) Process(
+	ctx context.Context,
+	input []byte,
+	params models.ProcessingParams,
+) (*Result, error) {
+	imageProcessor, err := ut.NewImageProcessorFromParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	output, artifacts, err := imageProcessor.CreatePrimitiveArt(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Data:      output,
+		MimeType:  imageProcessor.Output.Format.MimeType(),
+		Artifacts: artifacts,
+	}, nil
+}
+
+func primitiveStyles() []string {
+	var styles []string
+	for _, style := range ut.Styles {
+		if ut.EffectForStyle(style) == ut.EffectPrimitive {
+			styles = append(styles, style)
+		}
+	}
+	return styles
+}
+
+func bound(v float64) *float64 {
+	return &v
+}
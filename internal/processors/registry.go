@@ -0,0 +1,121 @@
+package processors
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
+)
+
+// Processor - эффект, применяемый воркером к исходному изображению
+type Processor interface {
+	Name() string
+	Description() string
+	Params() []models.EffectParam
+	Validate(params models.ProcessingParams) error
+	Process(
+		ctx context.Context,
+		input []byte,
+		params models.ProcessingParams,
+	) (*Result, error)
+}
+
+// Result - закодированный результат эффекта и дополнительные файлы
+type Result struct {
+	Data      []byte
+	MimeType  string
+	Artifacts []ut.Artifact
+}
+
+type Registry struct {
+	mu         sync.RWMutex
+	processors map[string]Processor
+	order      []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		processors: make(map[string]Processor),
+	}
+}
+
+// NewDefaultRegistry - реестр со всеми встроенными эффектами
+func NewDefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.MustRegister(NewPrimitiveProcessor())
+	r.MustRegister(NewPencilProcessor())
+	return r
+}
+
+func (r *Registry) Register(p Processor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.processors[p.Name()]; ok {
+		return fmt.Errorf("processor %q already registered", p.Name())
+	}
+
+	r.processors[p.Name()] = p
+	r.order = append(r.order, p.Name())
+	return nil
+}
+
+func (r *Registry) MustRegister(p Processor) {
+	if err := r.Register(p); err != nil {
+		panic(err)
+	}
+}
+
+func (r *Registry) Get(name string) (Processor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.processors[name]
+	if !ok {
+		return nil, fmt.Errorf(
+			"%w: unknown effect %q",
+			ut.ErrInvalidParams,
+			name,
+		)
+	}
+	return p, nil
+}
+
+// Resolve - процессор для задачи: явный effect или эффект по стилю
+func (r *Registry) Resolve(params models.ProcessingParams) (Processor, error) {
+	return r.Get(EffectName(params))
+}
+
+// Validate - проверка параметров процессором, который их будет исполнять
+func (r *Registry) Validate(params models.ProcessingParams) error {
+	p, err := r.Resolve(params)
+	if err != nil {
+		return err
+	}
+	return p.Validate(params)
+}
+
+func (r *Registry) List() []models.EffectInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	effects := make([]models.EffectInfo, 0, len(r.order))
+	for _, name := range r.order {
+		p := r.processors[name]
+		effects = append(effects, models.EffectInfo{
+			Name:        p.Name(),
+			Description: p.Description(),
+			Params:      p.Params(),
+		})
+	}
+	return effects
+}
+
+func EffectName(params models.ProcessingParams) string {
+	if params.Effect != "" {
+		return params.Effect
+	}
+	return string(ut.EffectForStyle(params.Style))
+}
@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrBatchNotFound = errors.New("batch not found")
+
+func (r *RedisRepository) SaveBatch(
+	ctx context.Context,
+	batch *models.Batch,
+) error {
+	key := fmt.Sprintf("batch:%s", batch.ID)
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	if err := r.client.Set(ctx, key, data, 24*time.Hour).Err(); err != nil {
+		return fmt.Errorf("failed to save batch: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RedisRepository) GetBatch(
+	ctx context.Context,
+	batchID string,
+) (*models.Batch, error) {
+	key := fmt.Sprintf("batch:%s", batchID)
+	data, err := r.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("batch %s: %w", batchID, ErrBatchNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get batch %s from Redis: %w", batchID, err)
+	}
+
+	var batch models.Batch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("unmarshal batch %s: %w", batchID, err)
+	}
+
+	return &batch, nil
+}
+
+// GetTasks - задачи одним MGET; на месте истёкших задач nil
+func (r *RedisRepository) GetTasks(
+	ctx context.Context,
+	taskIDs []string,
+) ([]*models.S3FileTask, error) {
+	if len(taskIDs) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(taskIDs))
+	for i, id := range taskIDs {
+		keys[i] = fmt.Sprintf("task:%s", id)
+	}
+
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get %d tasks from Redis: %w", len(keys), err)
+	}
+
+	tasks := make([]*models.S3FileTask, len(values))
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var task models.S3FileTask
+		if err := json.Unmarshal([]byte(data), &task); err != nil {
+			return nil, fmt.Errorf("unmarshal task %s: %w", taskIDs[i], err)
+		}
+		tasks[i] = &task
+	}
+
+	return tasks, nil
+}
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+func resultCacheKey(key string) string {
+	return fmt.Sprintf("result-cache:%s", key)
+}
+
+// GetCachedResult - nil без ошибки, если записи нет
+func (r *RedisRepository) GetCachedResult(
+	ctx context.Context,
+	key string,
+) (*models.CachedResult, error) {
+	data, err := r.client.Get(ctx, resultCacheKey(key)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get cached result %s: %w", key, err)
+	}
+
+	var result models.CachedResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal cached result %s: %w", key, err)
+	}
+
+	return &result, nil
+}
+
+func (r *RedisRepository) SaveCachedResult(
+	ctx context.Context,
+	key string,
+	result *models.CachedResult,
+	ttl time.Duration,
+) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cached result: %w", err)
+	}
+
+	if err := r.client.Set(ctx, resultCacheKey(key), data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save cached result: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RedisRepository) DeleteCachedResult(
+	ctx context.Context,
+	key string,
+) error {
+	if err := r.client.Del(ctx, resultCacheKey(key)).Err(); err != nil {
+		return fmt.Errorf("delete cached result %s: %w", key, err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,6 +12,10 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+var ErrTaskNotFound = errors.New("task not found")
+
+const updateTaskMaxRetries = 10
+
 type RedisRepository struct {
 	client *redis.Client
 	cfg    *config.RedisConfig
@@ -65,7 +70,7 @@ func (r *RedisRepository) GetTask(
 	key := fmt.Sprintf("task:%s", taskID)
 	data, err := r.client.Get(ctx, key).Bytes()
 	if err == redis.Nil {
-		return nil, fmt.Errorf("task %s not found in Redis", taskID)
+		return nil, fmt.Errorf("task %s: %w", taskID, ErrTaskNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get task %s from Redis: %w", taskID, err)
@@ -79,27 +84,115 @@ func (r *RedisRepository) GetTask(
 	return &task, nil
 }
 
+// UpdateTask - атомарное чтение-изменение-запись задачи (WATCH/MULTI),
+// конкурентные изменения не теряются: при конфликте попытка повторяется
 func (r *RedisRepository) UpdateTask(
 	ctx context.Context,
 	taskID string,
 	updateFunc func(*models.S3FileTask) error,
-) error {
-	currentTask, err := r.GetTask(ctx, taskID)
-	if err != nil {
-		return fmt.Errorf("task %s not found, nothing to update", taskID)
+) (*models.S3FileTask, error) {
+	key := fmt.Sprintf("task:%s", taskID)
+	var updated models.S3FileTask
+
+	txf := func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			return fmt.Errorf(
+				"task %s: %w, nothing to update",
+				taskID,
+				ErrTaskNotFound,
+			)
+		}
+		if err != nil {
+			return fmt.Errorf("get task %s from Redis: %w", taskID, err)
+		}
+
+		updated = models.S3FileTask{}
+		if err := json.Unmarshal(data, &updated); err != nil {
+			return fmt.Errorf("unmarshal task %s: %w", taskID, err)
+		}
+
+		if err := updateFunc(&updated); err != nil {
+			return fmt.Errorf("update task %s: %w", taskID, err)
+		}
+
+		data, err = json.Marshal(&updated)
+		if err != nil {
+			return fmt.Errorf("failed to marshal task: %w", err)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, 24*time.Hour)
+			return nil
+		})
+		return err
 	}
 
-	if err := updateFunc(currentTask); err != nil {
-		return fmt.Errorf("update task %s: %w", taskID, err)
+	for attempt := 0; attempt < updateTaskMaxRetries; attempt++ {
+		err := r.client.Watch(ctx, txf, key)
+		if err == nil {
+			return &updated, nil
+		}
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		return nil, err
 	}
 
-	if err := r.SaveTask(ctx, currentTask); err != nil {
-		return fmt.Errorf("failed to update task %s: %w", taskID, err)
-	}
+	return nil, fmt.Errorf(
+		"failed to update task %s: too many concurrent updates",
+		taskID,
+	)
+}
 
+func (r *RedisRepository) PublishTaskEvent(
+	ctx context.Context,
+	taskID string,
+	payload []byte,
+) error {
+	channel := taskEventsChannel(taskID)
+	if err := r.client.Publish(ctx, channel, payload).Err(); err != nil {
+		return fmt.Errorf("publish to %s: %w", channel, err)
+	}
 	return nil
 }
 
+// SubscribeTaskEvents - подписка на события задачи; канал закрывается
+// после вызова возвращённой функции close или отмены ctx
+func (r *RedisRepository) SubscribeTaskEvents(
+	ctx context.Context,
+	taskID string,
+) (<-chan []byte, func() error, error) {
+	channel := taskEventsChannel(taskID)
+	pubsub := r.client.Subscribe(ctx, channel)
+
+	// дожидаемся подтверждения подписки, чтобы не потерять события
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, nil, fmt.Errorf("subscribe to %s: %w", channel, err)
+	}
+
+	messages := pubsub.Channel()
+	out := make(chan []byte)
+
+	go func() {
+		defer close(out)
+		for msg := range messages {
+			select {
+			case out <- []byte(msg.Payload):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, pubsub.Close, nil
+}
+
+func taskEventsChannel(taskID string) string {
+	return fmt.Sprintf("task-events:%s", taskID)
+}
+
 func (r *RedisRepository) Close(ctx context.Context) error {
 	done := make(chan error, 1)
 	go func() {
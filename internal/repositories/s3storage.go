@@ -6,13 +6,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
+	"net/url"
 	"time"
+	"unicode/utf8"
 
 	"github.com/BagRoman01/image-sketch-processor/internal/config"
 	"github.com/BagRoman01/image-sketch-processor/internal/logging"
 	"github.com/BagRoman01/image-sketch-processor/internal/models"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
@@ -20,6 +22,31 @@ import (
 	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
+var ErrFileNotFound = errors.New("file not found")
+
+// metaFileName - ключ пользовательских метаданных с исходным именем файла
+// (URL-encoded: метаданные S3 допускают только ASCII)
+const metaFileName = "filename"
+
+// maxMetaValue - предел значения метаданных после кодирования: S3
+// ограничивает все пользовательские метаданные объекта 2 КБ
+const maxMetaValue = 512
+
+// metaValue - URL-кодированное значение метаданных; длинное значение
+// обрезается по границе символа до maxMetaValue
+func metaValue(value string) string {
+	if len(value) > maxMetaValue {
+		value = value[:maxMetaValue]
+	}
+	escaped := url.QueryEscape(value)
+	for len(escaped) > maxMetaValue || !utf8.ValidString(value) {
+		_, size := utf8.DecodeLastRuneInString(value)
+		value = value[:len(value)-size]
+		escaped = url.QueryEscape(value)
+	}
+	return escaped
+}
+
 type S3Repository struct {
 	client        *s3.Client
 	presignClient *s3.PresignClient
@@ -146,26 +173,13 @@ func (s *S3Repository) isBucketAccessible(ctx context.Context) bool {
 	return err == nil
 }
 
-func (s *S3Repository) UploadFileStream(
+// UploadStream - потоковая загрузка с исходным именем файла в метаданных
+func (s *S3Repository) UploadStream(
 	ctx context.Context,
-	fileHeader *multipart.FileHeader,
 	key string,
+	body io.Reader,
+	contentType, fileName string,
 ) (*manager.UploadOutput, error) {
-	if s.cfg.MaxUploadSize > 0 && fileHeader.Size > s.cfg.MaxUploadSize {
-		return nil, fmt.Errorf(
-			"file size %d exceeds maximum allowed size %d",
-			fileHeader.Size,
-			s.cfg.MaxUploadSize,
-		)
-	}
-
-	file, err := fileHeader.Open()
-	if err != nil {
-		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
-	}
-	defer file.Close()
-
-	contentType := fileHeader.Header.Get("Content-Type")
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
@@ -173,8 +187,11 @@ func (s *S3Repository) UploadFileStream(
 	result, err := s.uploader.Upload(ctx, &s3.PutObjectInput{
 		Bucket:      aws.String(s.cfg.Bucket),
 		Key:         aws.String(key),
-		Body:        file,
+		Body:        body,
 		ContentType: aws.String(contentType),
+		Metadata: map[string]string{
+			metaFileName: metaValue(fileName),
+		},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload file %s to S3: %w", key, err)
@@ -183,6 +200,10 @@ func (s *S3Repository) UploadFileStream(
 	return result, nil
 }
 
+func (s *S3Repository) MaxUploadSize() int64 {
+	return s.cfg.MaxUploadSize
+}
+
 func (s *S3Repository) GetFileURL(key string) string {
 	if s.cfg.Endpoint != "" {
 		return fmt.Sprintf(
@@ -230,11 +251,44 @@ func (s *S3Repository) DownloadFile(
 	return result.Body, content, nil
 }
 
+// HeadFile - метаданные объекта без загрузки содержимого
+func (s *S3Repository) HeadFile(
+	ctx context.Context,
+	key string,
+) (*models.FileInfo, error) {
+	result, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.cfg.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *s3Types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, fmt.Errorf("%q: %w", key, ErrFileNotFound)
+		}
+		return nil, fmt.Errorf("failed to head %q in S3: %w", key, err)
+	}
+
+	fileName, err := url.QueryUnescape(result.Metadata[metaFileName])
+	if err != nil {
+		fileName = result.Metadata[metaFileName]
+	}
+
+	return &models.FileInfo{
+		FileName: fileName,
+		Content: models.Content{
+			ContentType:   aws.ToString(result.ContentType),
+			ContentLength: aws.ToInt64(result.ContentLength),
+		},
+	}, nil
+}
+
+// UploadData - загрузка из памяти; значения метаданных URL-кодируются
+// и обрезаются, как и имя файла в UploadStream
 func (s *S3Repository) UploadData(
 	ctx context.Context,
 	key string,
 	data []byte,
-	contentType string,
+	attrs models.ObjectAttrs,
 ) (*manager.UploadOutput, error) {
 	if s.cfg.MaxUploadSize > 0 && int64(len(data)) > s.cfg.MaxUploadSize {
 		return nil, fmt.Errorf(
@@ -243,12 +297,23 @@ func (s *S3Repository) UploadData(
 		)
 	}
 
-	result, err := s.uploader.Upload(ctx, &s3.PutObjectInput{
+	metadata := make(map[string]string, len(attrs.Metadata))
+	for name, value := range attrs.Metadata {
+		metadata[name] = metaValue(value)
+	}
+
+	input := &s3.PutObjectInput{
 		Bucket:      aws.String(s.cfg.Bucket),
 		Key:         aws.String(key),
 		Body:        bytes.NewReader(data),
-		ContentType: aws.String(contentType),
-	})
+		ContentType: aws.String(attrs.ContentType),
+		Metadata:    metadata,
+	}
+	if attrs.ContentDisposition != "" {
+		input.ContentDisposition = aws.String(attrs.ContentDisposition)
+	}
+
+	result, err := s.uploader.Upload(ctx, input)
 	return result, err
 }
 
@@ -270,3 +335,228 @@ func (s *S3Repository) GenerateDownloadURL(
 
 	return request.URL, nil
 }
+
+// PresignUploadPut - ссылка для PUT одного объекта; Content-Type,
+// Content-Length и имя файла входят в подпись, другие S3 не примет
+func (s *S3Repository) PresignUploadPut(
+	ctx context.Context,
+	key, contentType, fileName string,
+	size int64,
+) (*v4.PresignedHTTPRequest, error) {
+	request, err := s.presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.cfg.Bucket),
+		Key:           aws.String(key),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(size),
+		Metadata: map[string]string{
+			metaFileName: metaValue(fileName),
+		},
+	}, func(opts *s3.PresignOptions) {
+		opts.Expires = s.PresignExpiry()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign upload %q: %w", key, err)
+	}
+	return request, nil
+}
+
+// PresignUploadPost - политика для загрузки HTML-формой: ключ, тип
+// содержимого и размер до maxSize ограничены условиями политики
+func (s *S3Repository) PresignUploadPost(
+	ctx context.Context,
+	key, contentType, fileName string,
+	maxSize int64,
+) (*s3.PresignedPostRequest, error) {
+	fileNameMeta := metaValue(fileName)
+
+	request, err := s.presignClient.PresignPostObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.cfg.Bucket),
+		Key:    aws.String(key),
+	}, func(opts *s3.PresignPostOptions) {
+		opts.Expires = s.PresignExpiry()
+		opts.Conditions = []any{
+			[]any{"content-length-range", 1, maxSize},
+			map[string]string{"Content-Type": contentType},
+			map[string]string{"x-amz-meta-" + metaFileName: fileNameMeta},
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign upload %q: %w", key, err)
+	}
+
+	// поля из условий политики форма должна отправить как есть
+	request.Values["Content-Type"] = contentType
+	request.Values["x-amz-meta-"+metaFileName] = fileNameMeta
+	return request, nil
+}
+
+func (s *S3Repository) PresignExpiry() time.Duration {
+	return time.Duration(s.cfg.PresignExpirySec) * time.Second
+}
+
+// CreateMultipartUpload - начало загрузки по частям, возвращает UploadId
+func (s *S3Repository) CreateMultipartUpload(
+	ctx context.Context,
+	key, contentType, fileName string,
+) (string, error) {
+	result, err := s.client.CreateMultipartUpload(
+		ctx,
+		&s3.CreateMultipartUploadInput{
+			Bucket:      aws.String(s.cfg.Bucket),
+			Key:         aws.String(key),
+			ContentType: aws.String(contentType),
+			Metadata: map[string]string{
+				metaFileName: metaValue(fileName),
+			},
+		},
+	)
+	if err != nil {
+		return "", fmt.Errorf(
+			"failed to create multipart upload %q: %w",
+			key,
+			err,
+		)
+	}
+	return aws.ToString(result.UploadId), nil
+}
+
+// UploadPart - одна часть загрузки, возвращает ETag части
+func (s *S3Repository) UploadPart(
+	ctx context.Context,
+	key, uploadID string,
+	number int,
+	data []byte,
+) (string, error) {
+	result, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(s.cfg.Bucket),
+		Key:           aws.String(key),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    aws.Int32(int32(number)),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+	})
+	if err != nil {
+		return "", fmt.Errorf(
+			"failed to upload part %d of %q: %w",
+			number,
+			key,
+			err,
+		)
+	}
+	return aws.ToString(result.ETag), nil
+}
+
+// CompleteMultipartUpload - сборка объекта из частей (по возрастанию номера)
+func (s *S3Repository) CompleteMultipartUpload(
+	ctx context.Context,
+	key, uploadID string,
+	parts []models.UploadPart,
+) error {
+	completed := make([]s3Types.CompletedPart, len(parts))
+	for i, part := range parts {
+		completed[i] = s3Types.CompletedPart{
+			PartNumber: aws.Int32(int32(part.Number)),
+			ETag:       aws.String(part.ETag),
+		}
+	}
+
+	_, err := s.client.CompleteMultipartUpload(
+		ctx,
+		&s3.CompleteMultipartUploadInput{
+			Bucket:   aws.String(s.cfg.Bucket),
+			Key:      aws.String(key),
+			UploadId: aws.String(uploadID),
+			MultipartUpload: &s3Types.CompletedMultipartUpload{
+				Parts: completed,
+			},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to complete multipart upload %q: %w",
+			key,
+			err,
+		)
+	}
+	return nil
+}
+
+func (s *S3Repository) AbortMultipartUpload(
+	ctx context.Context,
+	key, uploadID string,
+) error {
+	_, err := s.client.AbortMultipartUpload(
+		ctx,
+		&s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(s.cfg.Bucket),
+			Key:      aws.String(key),
+			UploadId: aws.String(uploadID),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to abort multipart upload %q: %w",
+			key,
+			err,
+		)
+	}
+	return nil
+}
+
+// MultipartUpload - незавершённая загрузка по частям в бакете
+type MultipartUpload struct {
+	Key       string
+	UploadID  string
+	Initiated time.Time
+}
+
+// ListMultipartUploads - незавершённые загрузки по частям с ключами
+// под prefix
+func (s *S3Repository) ListMultipartUploads(
+	ctx context.Context,
+	prefix string,
+) ([]MultipartUpload, error) {
+	paginator := s3.NewListMultipartUploadsPaginator(
+		s.client,
+		&s3.ListMultipartUploadsInput{
+			Bucket: aws.String(s.cfg.Bucket),
+			Prefix: aws.String(prefix),
+		},
+	)
+
+	var uploads []MultipartUpload
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to list multipart uploads under %q: %w",
+				prefix,
+				err,
+			)
+		}
+		for _, upload := range page.Uploads {
+			uploads = append(uploads, MultipartUpload{
+				Key:       aws.ToString(upload.Key),
+				UploadID:  aws.ToString(upload.UploadId),
+				Initiated: aws.ToTime(upload.Initiated),
+			})
+		}
+	}
+	return uploads, nil
+}
+
+// MultipartPartSize - размер частей загрузки (не меньше 5 MB, минимума S3)
+func (s *S3Repository) MultipartPartSize() int64 {
+	return max(s.cfg.ChunkUploadSize, manager.MinUploadPartSize)
+}
+
+func (s *S3Repository) DeleteFile(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.cfg.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete %q from S3: %w", key, err)
+	}
+	return nil
+}
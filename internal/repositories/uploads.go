@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrUploadSessionNotFound = errors.New("upload session not found")
+
+// UploadSessionTTL - сколько живёт незавершённая загрузка по частям
+const UploadSessionTTL = 24 * time.Hour
+
+func uploadSessionKey(id string) string {
+	return fmt.Sprintf("upload-session:%s", id)
+}
+
+// части хранятся в отдельном hash, чтобы параллельные PUT частей не
+// конфликтовали при записи
+func uploadPartsKey(id string) string {
+	return fmt.Sprintf("upload-session:%s:parts", id)
+}
+
+func (r *RedisRepository) SaveUploadSession(
+	ctx context.Context,
+	session *models.UploadSession,
+) error {
+	stored := *session
+	stored.Parts = nil
+
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal upload session: %w", err)
+	}
+
+	key := uploadSessionKey(session.ID)
+	err = r.client.Set(ctx, key, data, time.Until(session.ExpiresAt)).Err()
+	if err != nil {
+		return fmt.Errorf("failed to save upload session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RedisRepository) GetUploadSession(
+	ctx context.Context,
+	sessionID string,
+) (*models.UploadSession, error) {
+	var (
+		sessionCmd *redis.StringCmd
+		partsCmd   *redis.MapStringStringCmd
+	)
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		sessionCmd = pipe.Get(ctx, uploadSessionKey(sessionID))
+		partsCmd = pipe.HGetAll(ctx, uploadPartsKey(sessionID))
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf(
+			"get upload session %s from Redis: %w",
+			sessionID,
+			err,
+		)
+	}
+
+	data, err := sessionCmd.Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf(
+			"upload session %s: %w",
+			sessionID,
+			ErrUploadSessionNotFound,
+		)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(
+			"get upload session %s from Redis: %w",
+			sessionID,
+			err,
+		)
+	}
+
+	var session models.UploadSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf(
+			"unmarshal upload session %s: %w",
+			sessionID,
+			err,
+		)
+	}
+
+	for field, value := range partsCmd.Val() {
+		var part models.UploadPart
+		if err := json.Unmarshal([]byte(value), &part); err != nil {
+			return nil, fmt.Errorf(
+				"unmarshal part %s of upload session %s: %w",
+				field,
+				sessionID,
+				err,
+			)
+		}
+		session.Parts = append(session.Parts, part)
+	}
+	sort.Slice(session.Parts, func(i, j int) bool {
+		return session.Parts[i].Number < session.Parts[j].Number
+	})
+
+	return &session, nil
+}
+
+// SaveUploadPart - принятая часть; повторная отправка перезаписывает её
+func (r *RedisRepository) SaveUploadPart(
+	ctx context.Context,
+	session *models.UploadSession,
+	part models.UploadPart,
+) error {
+	data, err := json.Marshal(part)
+	if err != nil {
+		return fmt.Errorf("failed to marshal upload part: %w", err)
+	}
+
+	key := uploadPartsKey(session.ID)
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, strconv.Itoa(part.Number), data)
+		pipe.ExpireAt(ctx, key, session.ExpiresAt)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf(
+			"save part %d of upload session %s: %w",
+			part.Number,
+			session.ID,
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (r *RedisRepository) DeleteUploadSession(
+	ctx context.Context,
+	sessionID string,
+) error {
+	err := r.client.Del(
+		ctx,
+		uploadSessionKey(sessionID),
+		uploadPartsKey(sessionID),
+	).Err()
+	if err != nil {
+		return fmt.Errorf("delete upload session %s: %w", sessionID, err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package routers
+
+import (
+	"github.com/BagRoman01/image-sketch-processor/internal/config"
+	"github.com/BagRoman01/image-sketch-processor/internal/handlers"
+	"github.com/BagRoman01/image-sketch-processor/internal/injectors"
+	"github.com/BagRoman01/image-sketch-processor/internal/middlewares"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAdminRoutes(
+	r *gin.RouterGroup,
+	cfg *config.AdminConfig,
+	serviceInjector *injectors.ServiceInjector,
+) {
+	handler := handlers.NewAdminHandler(serviceInjector)
+
+	admin := r.Group("/admin", middlewares.AdminAuthMiddleware(cfg.Token))
+	{
+		admin.GET("/dead-letters", handler.ListDeadLetters)
+		admin.GET("/dead-letters/:id", handler.GetDeadLetter)
+		admin.POST("/dead-letters/replay", handler.ReplayDeadLetters)
+	}
+}
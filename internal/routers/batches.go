@@ -0,0 +1,21 @@
+package routers
+
+import (
+	"github.com/BagRoman01/image-sketch-processor/internal/handlers"
+	"github.com/BagRoman01/image-sketch-processor/internal/injectors"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterBatchesRoutes(
+	r *gin.RouterGroup,
+	serviceInjector *injectors.ServiceInjector,
+) {
+	handler := handlers.NewBatchesHandler(serviceInjector)
+
+	batches := r.Group("/batches")
+	{
+		batches.POST("", handler.CreateBatch)
+		batches.GET("/:id", handler.GetBatch)
+		batches.GET("/:id/archive", handler.DownloadBatchArchive)
+	}
+}
@@ -0,0 +1,16 @@
+package routers
+
+import (
+	"github.com/BagRoman01/image-sketch-processor/internal/handlers"
+	"github.com/BagRoman01/image-sketch-processor/internal/injectors"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterEffectsRoutes(
+	r *gin.RouterGroup,
+	serviceInjector *injectors.ServiceInjector,
+) {
+	handler := handlers.NewEffectsHandler(serviceInjector)
+
+	r.GET("/effects", handler.ListEffects)
+}
@@ -13,4 +13,5 @@ func RegisterFilesRoutes(
 	handler := handlers.NewFilesHandler(serviceInjector)
 
 	r.POST("/files", handler.UploadFileStreaming)
+	r.POST("/files/:fileID/tasks", handler.CreateTaskForFile)
 }
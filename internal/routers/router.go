@@ -3,6 +3,7 @@ package routers
 import (
 	"time"
 
+	"github.com/BagRoman01/image-sketch-processor/internal/config"
 	"github.com/BagRoman01/image-sketch-processor/internal/injectors"
 	"github.com/BagRoman01/image-sketch-processor/internal/middlewares"
 	"github.com/gin-contrib/cors"
@@ -11,7 +12,10 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func SetupRouter(serviceInjector *injectors.ServiceInjector) *gin.Engine {
+func SetupRouter(
+	cfg *config.Config,
+	serviceInjector *injectors.ServiceInjector,
+) *gin.Engine {
 	r := gin.New()
 	r.Use(middlewares.LoggingMiddleware())
 	r.Use(cors.New(cors.Config{
@@ -29,7 +33,12 @@ func SetupRouter(serviceInjector *injectors.ServiceInjector) *gin.Engine {
 	api := r.Group("/api")
 	{
 		RegisterFilesRoutes(api, serviceInjector)
+		RegisterUploadsRoutes(api, serviceInjector)
+		RegisterUploadSessionsRoutes(api, serviceInjector)
 		RegisterTasksRoutes(api, serviceInjector)
+		RegisterBatchesRoutes(api, serviceInjector)
+		RegisterEffectsRoutes(api, serviceInjector)
+		RegisterAdminRoutes(api, &cfg.AdminConfig, serviceInjector)
 	}
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
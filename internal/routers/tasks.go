@@ -15,5 +15,9 @@ func RegisterTasksRoutes(
 	tasks := r.Group("/tasks")
 	{
 		tasks.GET("/:id", handler.GetTaskStatus)
+		tasks.GET("/:id/events", handler.StreamTaskEvents)
+		tasks.DELETE("/:id", handler.CancelTask)
+		tasks.POST("/:id/retry", handler.RetryTask)
+		tasks.GET("/:id/archive", handler.DownloadTaskArchive)
 	}
 }
@@ -0,0 +1,23 @@
+package routers
+
+import (
+	"github.com/BagRoman01/image-sketch-processor/internal/handlers"
+	"github.com/BagRoman01/image-sketch-processor/internal/injectors"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterUploadSessionsRoutes(
+	r *gin.RouterGroup,
+	serviceInjector *injectors.ServiceInjector,
+) {
+	handler := handlers.NewUploadSessionsHandler(serviceInjector)
+
+	sessions := r.Group("/upload-sessions")
+	{
+		sessions.POST("", handler.CreateSession)
+		sessions.GET("/:id", handler.GetSession)
+		sessions.PUT("/:id/parts/:number", handler.UploadPart)
+		sessions.POST("/:id/complete", handler.CompleteSession)
+		sessions.DELETE("/:id", handler.AbortSession)
+	}
+}
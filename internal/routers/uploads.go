@@ -0,0 +1,20 @@
+package routers
+
+import (
+	"github.com/BagRoman01/image-sketch-processor/internal/handlers"
+	"github.com/BagRoman01/image-sketch-processor/internal/injectors"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterUploadsRoutes(
+	r *gin.RouterGroup,
+	serviceInjector *injectors.ServiceInjector,
+) {
+	handler := handlers.NewUploadsHandler(serviceInjector)
+
+	uploads := r.Group("/uploads")
+	{
+		uploads.POST("", handler.PresignUpload)
+		uploads.POST("/:fileID/finalize", handler.FinalizeUpload)
+	}
+}
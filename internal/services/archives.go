@@ -0,0 +1,345 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/BagRoman01/image-sketch-processor/internal/repositories"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
+)
+
+// ErrArchiveEmpty - у задачи или пакета нет завершённых результатов
+var ErrArchiveEmpty = errors.New("no completed results to archive")
+
+const archiveManifestName = "manifest.json"
+
+// Archive - результаты, которые будут записаны в ZIP
+type Archive struct {
+	// Name - имя файла для Content-Disposition
+	Name     string
+	manifest models.ArchiveManifest
+	items    []archiveItem
+}
+
+type archiveItem struct {
+	file models.ArchiveFile
+	// dir - каталог внутри архива (для результатов с вариантами)
+	dir  string
+	stem string
+}
+
+type ArchiveService struct {
+	redisRepo   *repositories.RedisRepository
+	fileService *FileService
+	taskService *TaskService
+}
+
+func NewArchiveService(
+	redisRepo *repositories.RedisRepository,
+	fileService *FileService,
+	taskService *TaskService,
+) *ArchiveService {
+	return &ArchiveService{
+		redisRepo:   redisRepo,
+		fileService: fileService,
+		taskService: taskService,
+	}
+}
+
+// TaskArchive - результаты одной задачи: основной или все варианты
+func (s *ArchiveService) TaskArchive(
+	ctx context.Context,
+	taskID string,
+) (*Archive, error) {
+	task, err := s.taskService.GetTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := newArchive("task", task.ID)
+	archive.addTask(task, false, newNamer())
+	if len(archive.items) == 0 {
+		return nil, fmt.Errorf("task %s: %w", taskID, ErrArchiveEmpty)
+	}
+	return archive, nil
+}
+
+// BatchArchive - результаты всех завершённых задач пакета
+func (s *ArchiveService) BatchArchive(
+	ctx context.Context,
+	batchID string,
+) (*Archive, error) {
+	batch, err := s.redisRepo.GetBatch(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.redisRepo.GetTasks(ctx, batch.TaskIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := newArchive("batch", batch.ID)
+	names := newNamer()
+	for i, task := range tasks {
+		if task == nil {
+			archive.skip(models.ArchiveSkip{
+				TaskID: batch.TaskIDs[i],
+				Reason: "task expired",
+			})
+			continue
+		}
+		archive.addTask(task, true, names)
+	}
+
+	if len(archive.items) == 0 {
+		return nil, fmt.Errorf("batch %s: %w", batchID, ErrArchiveEmpty)
+	}
+	return archive, nil
+}
+
+func newArchive(kind, id string) *Archive {
+	return &Archive{
+		Name: kind + "-" + id + ".zip",
+		manifest: models.ArchiveManifest{
+			Kind: kind,
+			ID:   id,
+		},
+	}
+}
+
+func (a *Archive) skip(entry models.ArchiveSkip) {
+	a.manifest.Skipped = append(a.manifest.Skipped, entry)
+}
+
+// addTask - результаты задачи. В пакете каждая задача получает свой
+// каталог по имени исходника, если у неё есть варианты
+func (a *Archive) addTask(task *models.S3FileTask, inBatch bool, names *namer) {
+	source := task.S3FileInfo.FileName
+	stem := strings.TrimSuffix(safeName(source), path.Ext(source))
+	if stem == "" {
+		stem = task.ID
+	}
+
+	if task.Status != models.TaskStatusCompleted &&
+		len(task.Variants) == 0 {
+		a.skip(models.ArchiveSkip{
+			TaskID:     task.ID,
+			SourceName: source,
+			Status:     task.Status,
+			Reason:     "task is not completed",
+		})
+		return
+	}
+
+	if len(task.Variants) == 0 {
+		file := models.ArchiveFile{
+			TaskID:       task.ID,
+			SourceName:   source,
+			ProcessedKey: task.ProcessedKey,
+			Params:       task.Params,
+		}
+		stem := names.unique(stem + "-sketch")
+		a.items = append(a.items, archiveItem{file: file, stem: stem})
+		a.addArtifacts(file, task.Artifacts, "", stem)
+		return
+	}
+
+	dir := ""
+	if inBatch {
+		dir = names.unique(stem)
+	}
+	for _, variant := range task.Variants {
+		if variant.Status != models.TaskStatusCompleted {
+			a.skip(models.ArchiveSkip{
+				TaskID:     task.ID,
+				Variant:    variant.Name,
+				SourceName: source,
+				Status:     variant.Status,
+				Reason:     "variant is not completed",
+			})
+			continue
+		}
+
+		file := models.ArchiveFile{
+			TaskID:       task.ID,
+			Variant:      variant.Name,
+			SourceName:   source,
+			ProcessedKey: variant.ProcessedKey,
+			ContentType:  variant.MimeType,
+			Params:       task.Params.Merge(variant.Params),
+		}
+		a.items = append(a.items, archiveItem{
+			file: file,
+			dir:  dir,
+			stem: variant.Name,
+		})
+		a.addArtifacts(file, variant.Artifacts, dir, variant.Name)
+	}
+}
+
+// addArtifacts - дополнительные файлы результата рядом с ним:
+// <stem>.<artifact>.<ext>
+func (a *Archive) addArtifacts(
+	file models.ArchiveFile,
+	artifacts []models.TaskArtifact,
+	dir, stem string,
+) {
+	for _, artifact := range artifacts {
+		file.Artifact = artifact.Name
+		file.ProcessedKey = artifact.Key
+		file.ContentType = artifact.MimeType
+		a.items = append(a.items, archiveItem{
+			file: file,
+			dir:  dir,
+			stem: stem + "." + safeName(artifact.Name),
+		})
+	}
+}
+
+// WriteArchive - потоковая запись ZIP в w: объекты читаются из S3 по
+// одному и сразу пишутся в архив. manifest.json пишется последним,
+// чтобы учесть объекты, которые не удалось прочитать
+func (s *ArchiveService) WriteArchive(
+	ctx context.Context,
+	w io.Writer,
+	archive *Archive,
+) error {
+	logger := logging.LoggerFromContext(ctx)
+	zw := zip.NewWriter(w)
+
+	manifest := archive.manifest
+	manifest.GeneratedAt = time.Now()
+
+	for _, item := range archive.items {
+		file, err := s.writeItem(ctx, zw, item)
+		if err != nil {
+			if errors.Is(err, errArchiveSource) {
+				logger.Warn("archive entry skipped",
+					"task_id", item.file.TaskID,
+					"key", item.file.ProcessedKey,
+					"error", err)
+				manifest.Skipped = append(manifest.Skipped, models.ArchiveSkip{
+					TaskID:     item.file.TaskID,
+					Variant:    item.file.Variant,
+					SourceName: item.file.SourceName,
+					Reason:     "result file is unavailable",
+				})
+				continue
+			}
+			return err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	mw, err := zw.Create(archiveManifestName)
+	if err != nil {
+		return fmt.Errorf("create manifest entry: %w", err)
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("finish archive: %w", err)
+	}
+
+	logger.Info("archive written",
+		"kind", manifest.Kind,
+		"id", manifest.ID,
+		"files", len(manifest.Files),
+		"skipped", len(manifest.Skipped))
+	return nil
+}
+
+// errArchiveSource - объект не удалось открыть, запись архива продолжается
+var errArchiveSource = errors.New("archive source unavailable")
+
+func (s *ArchiveService) writeItem(
+	ctx context.Context,
+	zw *zip.Writer,
+	item archiveItem,
+) (models.ArchiveFile, error) {
+	file := item.file
+
+	body, content, err := s.fileService.OpenFile(ctx, file.ProcessedKey)
+	if err != nil {
+		return file, fmt.Errorf("%w: %w", errArchiveSource, err)
+	}
+	defer body.Close()
+
+	if content.ContentType != "" {
+		file.ContentType = content.ContentType
+	}
+	file.Path = path.Join(
+		item.dir,
+		item.stem+ut.ExtensionForMime(file.ContentType, file.SourceName),
+	)
+
+	// изображения уже сжаты, deflate только тратит CPU; текстовые
+	// файлы (SVG, shapes.json) сжимаются хорошо
+	method := zip.Store
+	if file.ContentType == "image/svg+xml" ||
+		file.ContentType == "application/json" {
+		method = zip.Deflate
+	}
+	entry, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     file.Path,
+		Method:   method,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return file, fmt.Errorf("create entry %s: %w", file.Path, err)
+	}
+
+	file.Size, err = io.Copy(entry, body)
+	if err != nil {
+		return file, fmt.Errorf("copy %s: %w", file.ProcessedKey, err)
+	}
+	return file, nil
+}
+
+// namer - уникальные имена в архиве: повторы получают суффикс -2, -3...
+type namer struct {
+	used map[string]int
+}
+
+func newNamer() *namer {
+	return &namer{used: map[string]int{archiveManifestName: 1}}
+}
+
+func (n *namer) unique(name string) string {
+	key := strings.ToLower(name)
+	n.used[key]++
+	if n.used[key] == 1 {
+		return name
+	}
+	return name + "-" + strconv.Itoa(n.used[key])
+}
+
+// safeName - имя файла без каталогов и управляющих символов
+func safeName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" || name == ".." {
+		return ""
+	}
+	return name
+}
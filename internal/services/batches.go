@@ -0,0 +1,360 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/config"
+	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/BagRoman01/image-sketch-processor/internal/repositories"
+	"github.com/oklog/ulid/v2"
+)
+
+var (
+	ErrBatchNotFound = repositories.ErrBatchNotFound
+	ErrBatchTooLarge = errors.New("too many files in batch")
+	ErrBatchEmpty    = errors.New("no images accepted")
+)
+
+// imageExtensions - файлы архива, которые считаются изображениями
+var imageExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+	".webp": true, ".bmp": true, ".tif": true, ".tiff": true,
+}
+
+type BatchService struct {
+	cfg         *config.BatchConfig
+	redisRepo   *repositories.RedisRepository
+	fileService *FileService
+	taskService *TaskService
+}
+
+func NewBatchService(
+	cfg *config.BatchConfig,
+	redisRepo *repositories.RedisRepository,
+	fileService *FileService,
+	taskService *TaskService,
+) *BatchService {
+	return &BatchService{
+		cfg:         cfg,
+		redisRepo:   redisRepo,
+		fileService: fileService,
+		taskService: taskService,
+	}
+}
+
+// batchSource - один исходник пакета: загруженный файл или файл из ZIP
+type batchSource struct {
+	name string
+	size int64
+	open func() (io.ReadCloser, error)
+}
+
+// CreateBatch - задача на каждое изображение из files; ZIP-архивы
+// распаковываются. Файлы, которые не удалось принять, перечисляются в
+// Rejected; ErrBatchEmpty, если не принят ни один
+func (s *BatchService) CreateBatch(
+	ctx context.Context,
+	files []*multipart.FileHeader,
+	opts models.TaskOptions,
+) (*models.Batch, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	if err := s.fileService.ValidateOptions(opts); err != nil {
+		return nil, err
+	}
+
+	batch := &models.Batch{
+		ID:        ulid.Make().String(),
+		CreatedAt: time.Now(),
+		Params:    opts.Params,
+	}
+	opts.BatchID = batch.ID
+
+	sources, closeArchives, err := s.collectSources(files, batch)
+	defer closeArchives()
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) > s.cfg.MaxFiles {
+		return nil, fmt.Errorf(
+			"%w: %d images, limit is %d",
+			ErrBatchTooLarge,
+			len(sources),
+			s.cfg.MaxFiles,
+		)
+	}
+
+	// запись пакета до создания задач: если Redis недоступен, задачи не
+	// запускаются без пакета, через который их можно найти
+	if err := s.redisRepo.SaveBatch(ctx, batch); err != nil {
+		logger.Error("failed to save batch",
+			"batch_id", batch.ID,
+			"error", err)
+		return nil, err
+	}
+
+	taskIDs := make([]string, len(sources))
+	errs := make([]error, len(sources))
+
+	sem := make(chan struct{}, max(s.cfg.UploadConcurrency, 1))
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			taskIDs[i], errs[i] = s.createTask(ctx, src, opts)
+		}()
+	}
+	wg.Wait()
+
+	for i, src := range sources {
+		if errs[i] != nil {
+			logger.Warn("batch file rejected",
+				"batch_id", batch.ID,
+				"file", src.name,
+				"error", errs[i])
+			batch.Rejected = append(batch.Rejected, models.RejectedFile{
+				FileName: src.name,
+				Error:    errs[i].Error(),
+			})
+			continue
+		}
+		batch.TaskIDs = append(batch.TaskIDs, taskIDs[i])
+	}
+
+	if len(batch.TaskIDs) == 0 {
+		return batch, ErrBatchEmpty
+	}
+
+	if err := s.redisRepo.SaveBatch(ctx, batch); err != nil {
+		logger.Error("failed to save batch",
+			"batch_id", batch.ID,
+			"error", err)
+		s.cancelTasks(ctx, batch)
+		return nil, err
+	}
+
+	logger.Info("batch created",
+		"batch_id", batch.ID,
+		"tasks", len(batch.TaskIDs),
+		"rejected", len(batch.Rejected))
+	return batch, nil
+}
+
+// cancelTasks - отмена задач пакета, который не удалось сохранить:
+// клиент не получил его ID и не узнает о результатах
+func (s *BatchService) cancelTasks(ctx context.Context, batch *models.Batch) {
+	logger := logging.LoggerFromContext(ctx)
+
+	for _, taskID := range batch.TaskIDs {
+		if _, err := s.taskService.CancelTask(ctx, taskID); err != nil {
+			logger.Error("failed to cancel task of unsaved batch",
+				"batch_id", batch.ID,
+				"task_id", taskID,
+				"error", err)
+		}
+	}
+}
+
+func (s *BatchService) createTask(
+	ctx context.Context,
+	src batchSource,
+	opts models.TaskOptions,
+) (string, error) {
+	body, err := src.open()
+	if err != nil {
+		return "", fmt.Errorf("open %s: %w", src.name, err)
+	}
+	defer body.Close()
+
+	info, err := s.fileService.UploadSource(
+		ctx,
+		body,
+		src.name,
+		src.size,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	task, err := s.taskService.CreateFileProcessingTask(ctx, *info, opts)
+	if err != nil {
+		return "", err
+	}
+	return task.ID, nil
+}
+
+// collectSources - список исходников; открытые архивы закрываются
+// возвращённой функцией после загрузки всех файлов
+func (s *BatchService) collectSources(
+	files []*multipart.FileHeader,
+	batch *models.Batch,
+) ([]batchSource, func(), error) {
+	var (
+		sources []batchSource
+		opened  []io.Closer
+	)
+	closeAll := func() {
+		for _, c := range opened {
+			c.Close()
+		}
+	}
+
+	for _, fh := range files {
+		if !isZip(fh) {
+			sources = append(sources, batchSource{
+				name: fh.Filename,
+				size: fh.Size,
+				open: func() (io.ReadCloser, error) {
+					return fh.Open()
+				},
+			})
+			continue
+		}
+
+		file, err := fh.Open()
+		if err != nil {
+			return nil, closeAll, fmt.Errorf("open %s: %w", fh.Filename, err)
+		}
+		opened = append(opened, file)
+
+		archive, err := zip.NewReader(file, fh.Size)
+		if err != nil {
+			batch.Rejected = append(batch.Rejected, models.RejectedFile{
+				FileName: fh.Filename,
+				Error:    "invalid ZIP archive: " + err.Error(),
+			})
+			continue
+		}
+
+		for _, entry := range archive.File {
+			if entry.FileInfo().IsDir() || isHiddenEntry(entry.Name) {
+				continue
+			}
+
+			ext := strings.ToLower(path.Ext(entry.Name))
+			if !imageExtensions[ext] {
+				batch.Rejected = append(batch.Rejected, models.RejectedFile{
+					FileName: entry.Name,
+					Error:    "not an image",
+				})
+				continue
+			}
+
+			sources = append(sources, batchSource{
+				name: path.Base(entry.Name),
+				size: int64(entry.UncompressedSize64),
+				// размер распакованного файла проверяется archive/zip
+				open: entry.Open,
+			})
+		}
+	}
+
+	return sources, closeAll, nil
+}
+
+func isZip(fh *multipart.FileHeader) bool {
+	switch fh.Header.Get("Content-Type") {
+	case "application/zip", "application/x-zip-compressed":
+		return true
+	}
+	return strings.EqualFold(path.Ext(fh.Filename), ".zip")
+}
+
+// isHiddenEntry - служебные файлы архиваторов (__MACOSX, .DS_Store)
+func isHiddenEntry(name string) bool {
+	if strings.HasPrefix(name, "__MACOSX/") {
+		return true
+	}
+	return strings.HasPrefix(path.Base(name), ".")
+}
+
+// GetBatch - пакет со статусами и счётчиками дочерних задач
+func (s *BatchService) GetBatch(
+	ctx context.Context,
+	batchID string,
+) (*models.BatchResponse, error) {
+	batch, err := s.redisRepo.GetBatch(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.redisRepo.GetTasks(ctx, batch.TaskIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.BatchResponse{
+		ID:        batch.ID,
+		CreatedAt: batch.CreatedAt,
+		Total:     len(batch.TaskIDs),
+		Counts:    map[models.TaskStatus]int{},
+		Tasks:     make([]models.BatchTaskSummary, 0, len(tasks)),
+		Rejected:  batch.Rejected,
+	}
+
+	for i, task := range tasks {
+		if task == nil {
+			resp.Expired++
+			resp.Tasks = append(resp.Tasks, models.BatchTaskSummary{
+				TaskID: batch.TaskIDs[i],
+				Error:  "task expired",
+			})
+			continue
+		}
+
+		resp.Counts[task.Status]++
+		summary := models.BatchTaskSummary{
+			TaskID:       task.ID,
+			FileName:     task.S3FileInfo.FileName,
+			Status:       task.Status,
+			ProcessedKey: task.ProcessedKey,
+			DownloadURL:  task.DownloadURL,
+			Variants:     len(task.Variants),
+			Error:        task.Error,
+		}
+		if task.Progress != nil {
+			summary.Percent = task.Progress.Percent
+		}
+		resp.Tasks = append(resp.Tasks, summary)
+	}
+
+	resp.Status = batchStatus(resp.Counts, resp.Total-resp.Expired)
+	return resp, nil
+}
+
+// batchStatus - сводный статус: pending, пока ни одна задача не начата;
+// processing, пока есть незавершённые; далее по числу успешных
+func batchStatus(counts map[models.TaskStatus]int, total int) models.BatchStatus {
+	completed := counts[models.TaskStatusCompleted]
+	finished := completed +
+		counts[models.TaskStatusFailed] +
+		counts[models.TaskStatusCancelled]
+
+	switch {
+	case total == 0:
+		return models.BatchStatusFailed
+	case counts[models.TaskStatusPending] == total:
+		return models.BatchStatusPending
+	case finished < total:
+		return models.BatchStatusProcessing
+	case completed == total:
+		return models.BatchStatusCompleted
+	case completed == 0:
+		return models.BatchStatusFailed
+	default:
+		return models.BatchStatusPartial
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/config"
+	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/BagRoman01/image-sketch-processor/internal/processors"
+	"github.com/BagRoman01/image-sketch-processor/internal/repositories"
+)
+
+// ResultCache - готовые результаты по (SHA-256 исходника, эффект,
+// параметры). Ошибки кэша не мешают обработке: промах, и задача
+// выполняется как обычно
+type ResultCache struct {
+	cfg        *config.CacheConfig
+	redisRepo  *repositories.RedisRepository
+	s3Repo     *repositories.S3Repository
+	processors *processors.Registry
+}
+
+func NewResultCache(
+	cfg *config.CacheConfig,
+	redisRepo *repositories.RedisRepository,
+	s3Repo *repositories.S3Repository,
+	processors *processors.Registry,
+) *ResultCache {
+	return &ResultCache{
+		cfg:        cfg,
+		redisRepo:  redisRepo,
+		s3Repo:     s3Repo,
+		processors: processors,
+	}
+}
+
+// ContentHash - SHA-256 содержимого в hex
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// key - хэш исходника и хэш параметров вместе с именем эффекта, в
+// который они разрешаются
+func (c *ResultCache) key(
+	contentHash string,
+	params models.ProcessingParams,
+) (string, bool) {
+	if !c.cfg.Enabled || contentHash == "" {
+		return "", false
+	}
+
+	processor, err := c.processors.Resolve(params)
+	if err != nil {
+		return "", false
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", false
+	}
+
+	h := sha256.New()
+	h.Write([]byte(processor.Name()))
+	h.Write([]byte{0})
+	h.Write(data)
+	return contentHash + ":" + hex.EncodeToString(h.Sum(nil)), true
+}
+
+// Lookup - готовый результат или nil. Запись, объект которой уже
+// удалён из хранилища, удаляется
+func (c *ResultCache) Lookup(
+	ctx context.Context,
+	contentHash string,
+	params models.ProcessingParams,
+) *models.CachedResult {
+	logger := logging.LoggerFromContext(ctx)
+
+	key, ok := c.key(contentHash, params)
+	if !ok {
+		return nil
+	}
+
+	cached, err := c.redisRepo.GetCachedResult(ctx, key)
+	if err != nil {
+		logger.Warn("result cache lookup failed", "key", key, "error", err)
+		return nil
+	}
+	if cached == nil {
+		return nil
+	}
+
+	if _, err := c.s3Repo.HeadFile(ctx, cached.ProcessedKey); err != nil {
+		if errors.Is(err, repositories.ErrFileNotFound) {
+			logger.Info("cached result is gone, dropping entry",
+				"key", key,
+				"processed_key", cached.ProcessedKey)
+			c.redisRepo.DeleteCachedResult(ctx, key)
+		} else {
+			logger.Warn("failed to check cached result",
+				"key", key,
+				"error", err)
+		}
+		return nil
+	}
+
+	logger.Info("result cache hit",
+		"key", key,
+		"processed_key", cached.ProcessedKey,
+		"source_task_id", cached.TaskID)
+	return cached
+}
+
+func (c *ResultCache) Store(
+	ctx context.Context,
+	contentHash string,
+	params models.ProcessingParams,
+	result models.CachedResult,
+) {
+	key, ok := c.key(contentHash, params)
+	if !ok {
+		return
+	}
+
+	// ссылки на скачивание истекают, при выдаче из кэша они создаются заново
+	result.Artifacts = slices.Clone(result.Artifacts)
+	for i := range result.Artifacts {
+		result.Artifacts[i].DownloadURL = ""
+	}
+	result.CreatedAt = time.Now()
+	ttl := time.Duration(c.cfg.TTLSec) * time.Second
+	if err := c.redisRepo.SaveCachedResult(ctx, key, &result, ttl); err != nil {
+		logging.LoggerFromContext(ctx).Warn("failed to store cached result",
+			"key", key,
+			"error", err)
+	}
+}
+
+// DownloadURL - ссылка на кэшированный результат; пустая при ошибке,
+// как и для обычного результата
+func (c *ResultCache) DownloadURL(ctx context.Context, key string) string {
+	url, err := c.s3Repo.GenerateDownloadURL(ctx, key, 1*time.Hour)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(
+			"failed to generate download URL",
+			"key", key,
+			"error", err,
+		)
+	}
+	return url
+}
+
+// ArtifactURLs - дополнительные файлы кэшированного результата со свежими
+// ссылками на скачивание
+func (c *ResultCache) ArtifactURLs(
+	ctx context.Context,
+	artifacts []models.TaskArtifact,
+) []models.TaskArtifact {
+	if len(artifacts) == 0 {
+		return nil
+	}
+	out := slices.Clone(artifacts)
+	for i := range out {
+		out[i].DownloadURL = c.DownloadURL(ctx, out[i].Key)
+	}
+	return out
+}
+
+// applyCachedVariant - вариант, готовый результат которого найден в кэше
+func applyCachedVariant(
+	variant *models.TaskVariant,
+	cached *models.CachedResult,
+	downloadURL string,
+	artifacts []models.TaskArtifact,
+) {
+	variant.Status = models.TaskStatusCompleted
+	variant.ProcessedKey = cached.ProcessedKey
+	variant.MimeType = cached.MimeType
+	variant.Size = cached.Size
+	variant.Width = cached.Width
+	variant.Height = cached.Height
+	variant.DownloadURL = downloadURL
+	variant.Artifacts = artifacts
+	variant.CacheHit = true
+	variant.Error = ""
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/messaging/rabbitmq"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+)
+
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+const (
+	DefaultDeadLetterLimit = 100
+	MaxDeadLetterLimit     = 1000
+)
+
+// DeadLetterService - просмотр и повторный запуск сообщений из DLQ
+type DeadLetterService struct {
+	publisher   *rabbitmq.RabbitMQPublisher
+	taskService *TaskService
+}
+
+func NewDeadLetterService(
+	publisher *rabbitmq.RabbitMQPublisher,
+	taskService *TaskService,
+) *DeadLetterService {
+	return &DeadLetterService{
+		publisher:   publisher,
+		taskService: taskService,
+	}
+}
+
+func (s *DeadLetterService) Queue() string {
+	return s.publisher.DeadLetterQueue()
+}
+
+// List - первые limit сообщений; очередь при этом не меняется
+func (s *DeadLetterService) List(
+	ctx context.Context,
+	limit int,
+) ([]models.DeadLetter, error) {
+	messages := []models.DeadLetter{}
+	err := s.publisher.VisitDeadLetters(
+		ctx,
+		clampLimit(limit),
+		func(dl models.DeadLetter) (bool, bool) {
+			messages = append(messages, redactDeadLetter(dl))
+			return false, false
+		})
+	if err != nil {
+		return nil, fmt.Errorf("list dead letters: %w", err)
+	}
+	return messages, nil
+}
+
+func (s *DeadLetterService) Get(
+	ctx context.Context,
+	messageID string,
+) (*models.DeadLetter, error) {
+	var found *models.DeadLetter
+	err := s.publisher.VisitDeadLetters(
+		ctx,
+		MaxDeadLetterLimit,
+		func(dl models.DeadLetter) (bool, bool) {
+			if dl.MessageID != messageID {
+				return false, false
+			}
+			dl = redactDeadLetter(dl)
+			found = &dl
+			return false, true
+		})
+	if err != nil {
+		return nil, fmt.Errorf("get dead letter %q: %w", messageID, err)
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrDeadLetterNotFound, messageID)
+	}
+	return found, nil
+}
+
+// Replay - возврат выбранных (или всех при all) сообщений в очередь
+// обработки. Сообщение удаляется из DLQ только после успешной публикации;
+// неразобранные и не найденные в Redis задачи остаются в DLQ
+func (s *DeadLetterService) Replay(
+	ctx context.Context,
+	messageIDs []string,
+	all bool,
+) (*models.DeadLetterReplayResponse, error) {
+	logger := logging.LoggerFromContext(ctx)
+	resp := &models.DeadLetterReplayResponse{
+		Results: []models.DeadLetterReplayResult{},
+	}
+	pending := slices.Clone(messageIDs)
+
+	err := s.publisher.VisitDeadLetters(
+		ctx,
+		MaxDeadLetterLimit,
+		func(dl models.DeadLetter) (bool, bool) {
+			if !all {
+				i := slices.Index(pending, dl.MessageID)
+				if i < 0 {
+					return false, false
+				}
+				pending = slices.Delete(pending, i, i+1)
+			}
+
+			result := models.DeadLetterReplayResult{
+				MessageID: dl.MessageID,
+				TaskID:    dl.TaskID,
+			}
+			take := s.replayOne(ctx, dl, &result)
+			resp.Results = append(resp.Results, result)
+			if take {
+				resp.Replayed++
+			}
+			return take, !all && len(pending) == 0
+		})
+	if err != nil {
+		return resp, fmt.Errorf("replay dead letters: %w", err)
+	}
+
+	for _, id := range pending {
+		resp.Results = append(resp.Results, models.DeadLetterReplayResult{
+			MessageID: id,
+			Error:     ErrDeadLetterNotFound.Error(),
+		})
+	}
+
+	logger.Info("dead letters replayed",
+		"queue", s.Queue(),
+		"replayed", resp.Replayed,
+		"requested", len(resp.Results))
+	return resp, nil
+}
+
+func (s *DeadLetterService) replayOne(
+	ctx context.Context,
+	dl models.DeadLetter,
+	result *models.DeadLetterReplayResult,
+) bool {
+	if dl.Task == nil || dl.TaskID == "" {
+		result.Error = "message body is not a task"
+		return false
+	}
+
+	if _, err := s.taskService.RequeueTask(ctx, dl.TaskID); err != nil {
+		result.Error = err.Error()
+		return false
+	}
+
+	result.Replayed = true
+	return true
+}
+
+func redactDeadLetter(dl models.DeadLetter) models.DeadLetter {
+	if dl.Task != nil {
+		dl.Task = dl.Task.Redacted()
+	}
+	return dl
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultDeadLetterLimit
+	}
+	return min(limit, MaxDeadLetterLimit)
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/config"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
+)
+
+var (
+	ErrInvalidSourceURL = errors.New("invalid source_url")
+	// ErrSourceBlocked - адрес источника во внутренней сети
+	ErrSourceBlocked  = errors.New("source address is not allowed")
+	ErrSourceNotImage = errors.New("source is not an image")
+	// ErrSourceFetch - источник недоступен или ответил ошибкой
+	ErrSourceFetch = errors.New("failed to fetch source")
+)
+
+const sniffLen = 512
+
+// FetchedSource - ответ источника; Body читается не больше лимита
+type FetchedSource struct {
+	Body        io.ReadCloser
+	FileName    string
+	ContentType string
+	// Size - Content-Length ответа, 0 если не указан
+	Size int64
+
+	limited *limitedReader
+}
+
+// Exceeded - тело оказалось больше лимита (проверять после чтения)
+func (s *FetchedSource) Exceeded() bool {
+	return s.limited.exceeded
+}
+
+// ReadErr - ошибка чтения тела со стороны источника, если была
+func (s *FetchedSource) ReadErr() error {
+	return s.limited.err
+}
+
+// BytesRead - сколько байт тела прочитано
+func (s *FetchedSource) BytesRead() int64 {
+	return s.limited.read
+}
+
+type SourceFetcher struct {
+	cfg    *config.FetchConfig
+	client *http.Client
+}
+
+func NewSourceFetcher(cfg *config.FetchConfig) *SourceFetcher {
+	dialer := publicDialer(cfg.AllowPrivate, ErrSourceBlocked)
+
+	return &SourceFetcher{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: time.Duration(cfg.TimeoutSec) * time.Second,
+			Transport: &http.Transport{
+				// прокси из окружения обошёл бы проверку адреса
+				Proxy:                 nil,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ResponseHeaderTimeout: 15 * time.Second,
+				MaxIdleConns:          10,
+				IdleConnTimeout:       30 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > cfg.MaxRedirects {
+					return fmt.Errorf("stopped after %d redirects", cfg.MaxRedirects)
+				}
+				return validateSourceURL(req.URL)
+			},
+		},
+	}
+}
+
+// ParseSourceURL - source_url из запроса: абсолютный http(s) без
+// учётных данных
+func ParseSourceURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSourceURL, err)
+	}
+	if err := validateSourceURL(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func validateSourceURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf(
+			"%w: scheme must be http or https",
+			ErrInvalidSourceURL,
+		)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%w: host is required", ErrInvalidSourceURL)
+	}
+	if u.User != nil {
+		return fmt.Errorf(
+			"%w: credentials in URL are not allowed",
+			ErrInvalidSourceURL,
+		)
+	}
+	return nil
+}
+
+// Fetch - GET источника. Тип содержимого определяется по первым байтам,
+// а не по заголовку ответа; тело длиннее maxSize обрезается, см. Exceeded
+func (f *SourceFetcher) Fetch(
+	ctx context.Context,
+	source *url.URL,
+	maxSize int64,
+) (*FetchedSource, error) {
+	if f.cfg.MaxSize > 0 {
+		maxSize = f.cfg.MaxSize
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		source.String(),
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSourceURL, err)
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrSourceBlocked) ||
+			errors.Is(err, ErrInvalidSourceURL) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrSourceFetch, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf(
+			"%w: source responded with status %d",
+			ErrSourceFetch,
+			resp.StatusCode,
+		)
+	}
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		resp.Body.Close()
+		return nil, fmt.Errorf(
+			"%w: source is %d bytes, limit is %d",
+			ErrFileTooLarge,
+			resp.ContentLength,
+			maxSize,
+		)
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: read body: %w", ErrSourceFetch, err)
+	}
+	head = head[:n]
+
+	contentType := ut.SniffImageType(head)
+	if contentType == "" {
+		resp.Body.Close()
+		return nil, fmt.Errorf(
+			"%w: detected %s",
+			ErrSourceNotImage,
+			http.DetectContentType(head),
+		)
+	}
+
+	limited := &limitedReader{
+		r:     io.MultiReader(bytes.NewReader(head), resp.Body),
+		limit: maxSize,
+	}
+
+	return &FetchedSource{
+		Body:        readCloser{Reader: limited, Closer: resp.Body},
+		FileName:    sourceFileName(resp, contentType),
+		ContentType: contentType,
+		Size:        max(resp.ContentLength, 0),
+		limited:     limited,
+	}, nil
+}
+
+// sourceFileName - имя из Content-Disposition или последнего сегмента
+// пути (после редиректов); расширение по типу содержимого, если его нет
+func sourceFileName(resp *http.Response, contentType string) string {
+	if _, params, err := mime.ParseMediaType(
+		resp.Header.Get("Content-Disposition"),
+	); err == nil && params["filename"] != "" {
+		return safeName(params["filename"])
+	}
+
+	name := safeName(path.Base(resp.Request.URL.Path))
+	if name == "" {
+		name = "source"
+	}
+	if path.Ext(name) == "" {
+		name += ut.ExtensionForMime(contentType, "")
+	}
+	return name
+}
+
+// limitedReader - как io.LimitReader, но запоминает превышение лимита
+// вместо тихого обрезания
+type limitedReader struct {
+	r        io.Reader
+	limit    int64
+	read     int64
+	exceeded bool
+	err      error
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.limit > 0 && l.read > l.limit {
+		l.exceeded = true
+		return n, ErrFileTooLarge
+	}
+	if err != nil && err != io.EOF {
+		l.err = err
+	}
+	return n, err
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -1,32 +1,59 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
 	"io"
+	"mime"
 	"mime/multipart"
+	"path"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/BagRoman01/image-sketch-processor/internal/config"
 	"github.com/BagRoman01/image-sketch-processor/internal/logging"
 	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/BagRoman01/image-sketch-processor/internal/processors"
 	"github.com/BagRoman01/image-sketch-processor/internal/repositories"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/oklog/ulid/v2"
 )
 
+var (
+	ErrFileNotFound = repositories.ErrFileNotFound
+	ErrFileTooLarge = errors.New("file too large")
+)
+
 type FileService struct {
 	s3Repo      *repositories.S3Repository
 	taskService *TaskService
+	processors  *processors.Registry
+	fetcher     *SourceFetcher
+	imageCfg    *config.ImageConfig
 	entropy     *ulid.LockedMonotonicReader
 }
 
 func NewFileService(
 	s3Repo *repositories.S3Repository,
 	taskService *TaskService,
+	processors *processors.Registry,
+	fetcher *SourceFetcher,
+	imageCfg *config.ImageConfig,
 ) *FileService {
 	return &FileService{
-		s3Repo: s3Repo,
+		s3Repo:     s3Repo,
+		processors: processors,
+		fetcher:    fetcher,
+		imageCfg:   imageCfg,
 		entropy: &ulid.LockedMonotonicReader{
 			MonotonicReader: ulid.Monotonic(rand.Reader, 0),
 		},
@@ -37,10 +64,15 @@ func NewFileService(
 func (s *FileService) UploadFileStream(
 	ctx context.Context,
 	fileHeader *multipart.FileHeader,
+	opts models.TaskOptions,
 ) (*manager.UploadOutput, *models.S3FileTask, error) {
 	logger := logging.LoggerFromContext(ctx)
 
-	fileID := ulid.MustNew(ulid.Timestamp(time.Now()), s.entropy).String()
+	if err := s.ValidateOptions(opts); err != nil {
+		return nil, nil, err
+	}
+
+	fileID := s.newFileID()
 	key := "upload/" + fileID
 
 	logger.Debug(
@@ -49,7 +81,36 @@ func (s *FileService) UploadFileStream(
 		"original_file", fileHeader.Filename,
 	)
 
-	result, err := s.s3Repo.UploadFileStream(ctx, fileHeader, key)
+	if limit := s.s3Repo.MaxUploadSize(); limit > 0 && fileHeader.Size > limit {
+		return nil, nil, fmt.Errorf(
+			"%w: %s is %d bytes, limit is %d",
+			ErrFileTooLarge,
+			fileHeader.Filename,
+			fileHeader.Size,
+			limit,
+		)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer file.Close()
+
+	detected, body, err := s.inspectSource(ctx, file, fileHeader.Filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// хэш считается по ходу загрузки, для кэша результатов
+	hasher := sha256.New()
+	result, err := s.s3Repo.UploadStream(
+		ctx,
+		key,
+		io.TeeReader(body, hasher),
+		detected.MimeType,
+		fileHeader.Filename,
+	)
 	if err != nil {
 		logger.Error("S3 repository upload failed", "error", err, "key", key)
 		return nil, nil, err
@@ -57,22 +118,20 @@ func (s *FileService) UploadFileStream(
 
 	content := models.Content{
 		ContentLength: fileHeader.Size,
-		ContentType:   fileHeader.Header.Get("Content-Type"),
+		ContentType:   detected.MimeType,
 	}
 
 	fileInfo := models.S3FileInfo{
 		FileKey: key,
 		FileID:  fileID,
+		SHA256:  hex.EncodeToString(hasher.Sum(nil)),
 		FileInfo: models.FileInfo{
 			FileName: fileHeader.Filename,
 			Content:  content,
 		},
 	}
 
-	task, err := s.taskService.CreateFileProcessingTask(
-		ctx,
-		fileInfo,
-	)
+	task, err := s.taskService.CreateFileProcessingTask(ctx, fileInfo, opts)
 
 	if err != nil {
 		logger.Error(
@@ -86,33 +145,330 @@ func (s *FileService) UploadFileStream(
 	return result, task, nil
 }
 
+// UploadSource - загрузка исходника из произвольного потока (например,
+// файла из архива) в upload/<fileID> без создания задачи. Тип файла
+// определяется по содержимому
+func (s *FileService) UploadSource(
+	ctx context.Context,
+	source io.Reader,
+	fileName string,
+	size int64,
+) (*models.S3FileInfo, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	if limit := s.s3Repo.MaxUploadSize(); limit > 0 && size > limit {
+		return nil, fmt.Errorf(
+			"%w: %s is %d bytes, limit is %d",
+			ErrFileTooLarge,
+			fileName,
+			size,
+			limit,
+		)
+	}
+
+	detected, body, err := s.inspectSource(ctx, source, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	fileID := s.newFileID()
+	key := "upload/" + fileID
+
+	hasher := sha256.New()
+	if _, err := s.s3Repo.UploadStream(
+		ctx,
+		key,
+		io.TeeReader(body, hasher),
+		detected.MimeType,
+		fileName,
+	); err != nil {
+		logger.Error("S3 repository upload failed", "error", err, "key", key)
+		return nil, err
+	}
+
+	return &models.S3FileInfo{
+		FileKey: key,
+		FileID:  fileID,
+		SHA256:  hex.EncodeToString(hasher.Sum(nil)),
+		FileInfo: models.FileInfo{
+			FileName: fileName,
+			Content: models.Content{
+				ContentLength: size,
+				ContentType:   detected.MimeType,
+			},
+		},
+	}, nil
+}
+
+// UploadFromURL - скачивание исходника по source_url в upload/<fileID>
+// и создание задачи, как при обычной загрузке
+func (s *FileService) UploadFromURL(
+	ctx context.Context,
+	sourceURL string,
+	opts models.TaskOptions,
+) (*models.S3FileTask, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	if err := s.ValidateOptions(opts); err != nil {
+		return nil, err
+	}
+
+	source, err := ParseSourceURL(sourceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	fetched, err := s.fetcher.Fetch(ctx, source, s.s3Repo.MaxUploadSize())
+	if err != nil {
+		logger.Warn("failed to fetch source",
+			"source_url", source.Redacted(),
+			"error", err)
+		return nil, err
+	}
+	defer fetched.Body.Close()
+
+	fileInfo, err := s.UploadSource(
+		ctx,
+		fetched.Body,
+		fetched.FileName,
+		fetched.Size,
+	)
+	if fetched.Exceeded() {
+		if fileInfo != nil {
+			s.DeleteFile(ctx, fileInfo.FileKey)
+		}
+		return nil, fmt.Errorf(
+			"%w: source exceeds %d bytes",
+			ErrFileTooLarge,
+			s.s3Repo.MaxUploadSize(),
+		)
+	}
+	if readErr := fetched.ReadErr(); readErr != nil {
+		return nil, fmt.Errorf("%w: read body: %w", ErrSourceFetch, readErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fileInfo.SourceURL = source.Redacted()
+	fileInfo.Content.ContentLength = fetched.BytesRead()
+
+	logger.Info("source fetched",
+		"source_url", fileInfo.SourceURL,
+		"key", fileInfo.FileKey,
+		"size", fileInfo.Content.ContentLength,
+		"ct", fileInfo.Content.ContentType)
+
+	return s.taskService.CreateFileProcessingTask(ctx, *fileInfo, opts)
+}
+
+// FileURL - адрес объекта в хранилище
+func (s *FileService) FileURL(key string) string {
+	return s.s3Repo.GetFileURL(key)
+}
+
+// ValidateOptions - параметры, варианты и вебхук до загрузки файла
+func (s *FileService) ValidateOptions(opts models.TaskOptions) error {
+	if err := s.processors.Validate(opts.Params); err != nil {
+		return err
+	}
+	if err := ut.ValidateVariants(opts.Variants); err != nil {
+		return err
+	}
+	return ValidateCallback(opts.Callback)
+}
+
+// ImageLimits - ограничения размеров исходника из конфигурации
+func (s *FileService) ImageLimits() ut.ImageLimits {
+	return ut.ImageLimits{
+		MaxWidth:  s.imageCfg.MaxWidth,
+		MaxHeight: s.imageCfg.MaxHeight,
+		MaxPixels: s.imageCfg.MaxPixels,
+	}
+}
+
+// inspectSource - формат и размеры исходника по заголовку до записи в
+// S3; body отдаёт поток целиком, включая прочитанный заголовок
+func (s *FileService) inspectSource(
+	ctx context.Context,
+	source io.Reader,
+	fileName string,
+) (*ut.ImageInfo, io.Reader, error) {
+	info, body, err := ut.InspectImage(source, s.ImageLimits())
+	if err != nil {
+		logging.LoggerFromContext(ctx).Warn("source image rejected",
+			"file", fileName,
+			"error", err)
+		return nil, nil, err
+	}
+	return info, body, nil
+}
+
+func (s *FileService) newFileID() string {
+	return ulid.MustNew(ulid.Timestamp(time.Now()), s.entropy).String()
+}
+
+// CreateTaskForFile - новая задача над уже загруженным upload/<fileID>
+// без повторной загрузки файла
+func (s *FileService) CreateTaskForFile(
+	ctx context.Context,
+	fileID string,
+	opts models.TaskOptions,
+) (*models.S3FileTask, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	if err := s.ValidateOptions(opts); err != nil {
+		return nil, err
+	}
+
+	fileInfo, err := s.headUpload(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := s.taskService.CreateFileProcessingTask(ctx, *fileInfo, opts)
+	if err != nil {
+		logger.Error(
+			"failed to create processing task",
+			"error", err,
+			"key", fileInfo.FileKey,
+		)
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// headUpload - сведения о загруженном upload/<fileID> из S3
+func (s *FileService) headUpload(
+	ctx context.Context,
+	fileID string,
+) (*models.S3FileInfo, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	// fileID попадает в ключ S3, поэтому принимаем только ULID
+	if _, err := ulid.ParseStrict(fileID); err != nil {
+		return nil, fmt.Errorf("file %q: %w", fileID, ErrFileNotFound)
+	}
+	key := "upload/" + fileID
+
+	info, err := s.s3Repo.HeadFile(ctx, key)
+	if err != nil {
+		logger.Warn("source file unavailable", "key", key, "error", err)
+		return nil, err
+	}
+	if info.FileName == "" {
+		// загрузки до появления метаданных с именем файла
+		info.FileName = fileID
+	}
+
+	return &models.S3FileInfo{
+		FileKey:  key,
+		FileID:   fileID,
+		FileInfo: *info,
+	}, nil
+}
+
+// ProcessedFile - результат эффекта для записи в processed/
+type ProcessedFile struct {
+	Data     []byte
+	MimeType string
+	Effect   string
+	Params   models.ProcessingParams
+}
+
+// UploadProcessedFile - результат задачи в processed/<fileID>/<taskID>.<ext>,
+// чтобы задачи над одним файлом не перезаписывали друг друга
 func (s *FileService) UploadProcessedFile(
 	ctx context.Context,
 	task *models.S3FileTask,
-	data []byte,
+	out ProcessedFile,
 ) (string, error) {
-	logger := logging.LoggerFromContext(ctx)
+	return s.uploadProcessed(ctx, task, "", "", out)
+}
+
+// UploadVariantFile - вариант результата в
+// processed/<fileID>/<taskID>/<variant>.<ext>
+func (s *FileService) UploadVariantFile(
+	ctx context.Context,
+	task *models.S3FileTask,
+	variant string,
+	out ProcessedFile,
+) (string, error) {
+	return s.uploadProcessed(ctx, task, variant, "", out)
+}
+
+// UploadArtifacts - дополнительные файлы результата рядом с ним:
+// processed/<fileID>/<taskID>[/<variant>].<name>.<ext>
+func (s *FileService) UploadArtifacts(
+	ctx context.Context,
+	task *models.S3FileTask,
+	variant string,
+	out ProcessedFile,
+	artifacts []ut.Artifact,
+) ([]models.TaskArtifact, error) {
+	uploaded := make([]models.TaskArtifact, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		file := out
+		file.Data = artifact.Data
+		file.MimeType = artifact.MimeType
+
+		key, err := s.uploadProcessed(ctx, task, variant, artifact.Name, file)
+		if err != nil {
+			return uploaded, err
+		}
+		uploaded = append(uploaded, models.TaskArtifact{
+			Name:     artifact.Name,
+			Key:      key,
+			MimeType: artifact.MimeType,
+			Size:     int64(len(artifact.Data)),
+		})
+	}
+	return uploaded, nil
+}
 
-	processedKey := "processed/" + task.S3FileInfo.FileID
+func processedKey(
+	task *models.S3FileTask,
+	variant, artifact, mimeType string,
+) string {
+	key := "processed/" + task.S3FileInfo.FileID + "/" + task.ID
+	if variant != "" {
+		key += "/" + variant
+	}
+	if artifact != "" {
+		key += "." + artifact
+	}
+	return key + ut.ExtensionForMime(mimeType, "")
+}
+
+func (s *FileService) uploadProcessed(
+	ctx context.Context,
+	task *models.S3FileTask,
+	variant, artifact string,
+	out ProcessedFile,
+) (string, error) {
+	logger := logging.LoggerFromContext(ctx)
+	key := processedKey(task, variant, artifact, out.MimeType)
 
 	logger.Debug(
 		"uploading processed file",
 		"task_id", task.ID,
 		"input_key", task.S3FileInfo.FileKey,
-		"output_key", processedKey,
+		"output_key", key,
+		"mime", out.MimeType,
 	)
 
 	_, err := s.s3Repo.UploadData(
 		ctx,
-		processedKey,
-		data,
-		task.S3FileInfo.Content.ContentType,
+		key,
+		out.Data,
+		processedAttrs(task, variant, artifact, out),
 	)
 	if err != nil {
 		logger.Error(
 			"failed to upload processed file",
 			"task_id", task.ID,
-			"key", processedKey,
+			"key", key,
 			"error", err,
 		)
 		return "", fmt.Errorf("upload processed file: %w", err)
@@ -121,11 +477,75 @@ func (s *FileService) UploadProcessedFile(
 	logger.Debug(
 		"processed file uploaded successfully",
 		"task_id", task.ID,
-		"key", processedKey,
-		"size", len(data),
+		"key", key,
+		"size", len(out.Data),
 	)
 
-	return processedKey, nil
+	return key, nil
+}
+
+// processedAttrs - тип, имя для сохранения (по исходному файлу) и
+// метаданные, по которым объект можно сопоставить с задачей
+func processedAttrs(
+	task *models.S3FileTask,
+	variant, artifact string,
+	out ProcessedFile,
+) models.ObjectAttrs {
+	fileName := processedFileName(
+		task.S3FileInfo.FileName,
+		variant,
+		artifact,
+		out.MimeType,
+	)
+
+	metadata := map[string]string{
+		"filename":   fileName,
+		"task-id":    task.ID,
+		"source-key": task.S3FileInfo.FileKey,
+		"effect":     out.Effect,
+	}
+	if variant != "" {
+		metadata["variant"] = variant
+	}
+	if artifact != "" {
+		metadata["artifact"] = artifact
+	}
+	// параметры целиком не помещаются в 2 КБ метаданных S3, хэш
+	// позволяет сопоставить результат с параметрами задачи
+	if params, err := json.Marshal(out.Params); err == nil {
+		sum := sha256.Sum256(params)
+		metadata["params-sha256"] = hex.EncodeToString(sum[:])
+	}
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(out.Data)); err == nil {
+		metadata["width"] = strconv.Itoa(cfg.Width)
+		metadata["height"] = strconv.Itoa(cfg.Height)
+	}
+
+	return models.ObjectAttrs{
+		ContentType: out.MimeType,
+		ContentDisposition: mime.FormatMediaType(
+			"inline",
+			map[string]string{"filename": fileName},
+		),
+		Metadata: metadata,
+	}
+}
+
+// processedFileName - имя исходного файла с расширением результата:
+// photo.jpg -> photo.png, вариант small -> photo-small.png,
+// описание фигур -> photo.shapes.json
+func processedFileName(sourceName, variant, artifact, mimeType string) string {
+	stem := strings.TrimSuffix(sourceName, path.Ext(sourceName))
+	if stem == "" {
+		stem = "result"
+	}
+	if variant != "" {
+		stem += "-" + variant
+	}
+	if artifact != "" {
+		stem += "." + artifact
+	}
+	return stem + ut.ExtensionForMime(mimeType, "")
 }
 
 func (s *FileService) DownloadFile(
@@ -158,6 +578,32 @@ func (s *FileService) DownloadFile(
 	return data, nil
 }
 
+// OpenFile - потоковое чтение объекта; вызывающий закрывает reader
+func (s *FileService) OpenFile(
+	ctx context.Context,
+	key string,
+) (io.ReadCloser, *models.Content, error) {
+	body, content, err := s.s3Repo.DownloadFile(ctx, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open file %q: %w", key, err)
+	}
+	return body, content, nil
+}
+
+func (s *FileService) DeleteFile(ctx context.Context, key string) error {
+	logger := logging.LoggerFromContext(ctx)
+
+	if err := s.s3Repo.DeleteFile(ctx, key); err != nil {
+		logger.Error("failed to delete file from S3",
+			"key", key,
+			"error", err)
+		return err
+	}
+
+	logger.Info("file deleted", "key", key)
+	return nil
+}
+
 func (s *FileService) GenerateDownloadURL(
 	ctx context.Context,
 	key string,
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// blockedPrefixes - диапазоны, не покрытые методами netip.Addr
+// (IsPrivate, IsLoopback и т.д.)
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// publicDialer - соединения только с публичными адресами. Проверяется
+// уже разрешённый адрес: это защищает и от DNS rebinding, и от
+// редиректов на внутренние адреса. allowPrivate снимает проверку
+// (только для разработки); blocked - ошибка для запрещённого адреса
+func publicDialer(allowPrivate bool, blocked error) *net.Dialer {
+	return &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			if allowPrivate {
+				return nil
+			}
+			return checkPublicAddr(address, blocked)
+		},
+	}
+}
+
+func checkPublicAddr(address string, blocked error) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", blocked, address)
+	}
+
+	addr := addrPort.Addr().Unmap()
+	if addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return fmt.Errorf("%w: %s", blocked, addr)
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("%w: %s", blocked, addr)
+		}
+	}
+	return nil
+}
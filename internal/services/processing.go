@@ -2,40 +2,66 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"runtime/debug"
 	"time"
+	"unicode/utf8"
 
+	"github.com/BagRoman01/image-sketch-processor/internal/config"
 	"github.com/BagRoman01/image-sketch-processor/internal/messaging/rabbitmq"
 	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/BagRoman01/image-sketch-processor/internal/processors"
 	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 )
 
 type ProcessingService struct {
+	cfg              *config.ProcessingConfig
 	fileService      *FileService
 	taskService      *TaskService
-	imageProcessor   *ut.ImageProcessor
+	processors       *processors.Registry
 	rabbitmqConsumer *rabbitmq.RabbitMQConsumer
+	cache            *ResultCache
 }
 
 func NewProcessingService(
 	ctx context.Context,
+	cfg *config.ProcessingConfig,
 	fileService *FileService,
 	taskService *TaskService,
+	processors *processors.Registry,
 	rabbitmqConsumer *rabbitmq.RabbitMQConsumer,
+	cache *ResultCache,
 ) (*ProcessingService, error) {
-	imageProcessor := ut.NewImageProcessor()
-
 	return &ProcessingService{
-		imageProcessor:   imageProcessor,
+		cfg:              cfg,
+		processors:       processors,
 		rabbitmqConsumer: rabbitmqConsumer,
 		fileService:      fileService,
 		taskService:      taskService,
+		cache:            cache,
 	}, nil
 }
 
 func (w *ProcessingService) Start(ctx context.Context) error {
-	return w.rabbitmqConsumer.Consume(ctx, w.processTask)
+	return w.rabbitmqConsumer.Consume(ctx, w.handleTask)
+}
+
+// handleTask - обработка сообщения; отмена задачи во время обработки не
+// ошибка, сообщение подтверждается
+func (w *ProcessingService) handleTask(
+	ctx context.Context,
+	task *models.S3FileTask,
+) error {
+	err := w.processTask(ctx, task)
+	if errors.Is(err, ErrTaskCancelled) {
+		return nil
+	}
+	return err
 }
 
 func (w *ProcessingService) processTask(
@@ -46,40 +72,106 @@ func (w *ProcessingService) processTask(
 		"task_id", task.ID,
 		"file_key", task.S3FileInfo.FileKey)
 
-	err := w.taskService.SetTaskProcessing(ctx, task.ID)
-	if err != nil {
+	current, err := w.taskService.SetTaskProcessing(
+		ctx,
+		task.ID,
+		task.Run,
+		task.Attempts,
+	)
+	if errors.Is(err, ErrTaskCancelled) {
+		slog.Info("skipping cancelled task",
+			"task_id", task.ID,
+			"run", task.Run,
+			"reason", err)
+		return nil
+	}
+	if errors.Is(err, ErrTaskNotFound) {
 		return err
 	}
+	if err != nil {
+		// Redis недоступен - сообщение вернётся позже
+		return rabbitmq.Retryable(err)
+	}
+
+	// taskCtx отменяется, если задачу отменили через API во время работы;
+	// статусы в Redis пишутся через ctx, чтобы их не прервала та же отмена
+	taskCtx, stopWatch := w.watchCancellation(ctx, task)
+	defer stopWatch()
 
-	fileData, err := w.fileService.DownloadFile(ctx, task.S3FileInfo.FileKey)
+	fileData, err := w.fileService.DownloadFile(
+		taskCtx,
+		task.S3FileInfo.FileKey,
+	)
 	if err != nil {
-		return w.taskService.SetTaskFailed(
+		return w.handleFailure(ctx, task, "download failed", err)
+	}
+
+	// у прямых загрузок хэш при загрузке не считался
+	contentHash := task.S3FileInfo.SHA256
+	if contentHash == "" {
+		contentHash = ContentHash(fileData)
+	}
+
+	if len(current.Variants) > 0 {
+		return w.processVariants(
 			ctx,
-			task.ID,
-			fmt.Sprintf("download failed: %s", err),
+			taskCtx,
+			task,
+			current.Variants,
+			fileData,
+			contentHash,
 		)
 	}
 
-	processedData, err := w.imageProcessor.CreatePencilSketch(ctx, fileData)
+	if !task.NoCache {
+		if cached := w.cache.Lookup(ctx, contentHash, task.Params); cached != nil {
+			return w.completeFromCache(ctx, task, cached)
+		}
+	}
+
+	processor, err := w.processors.Resolve(task.Params)
 	if err != nil {
-		return w.taskService.SetTaskFailed(
-			ctx,
-			task.ID,
-			fmt.Sprintf("processing failed: %v", err),
-		)
+		return w.failTask(ctx, task, "invalid params", err)
+	}
+
+	result, err := w.runProcessor(
+		ut.WithProgress(taskCtx, w.progressReporter(ctx, task)),
+		processor,
+		fileData,
+		task.Params,
+	)
+	if err != nil {
+		return w.failTask(ctx, task, "processing failed", err)
+	}
+
+	out := ProcessedFile{
+		Data:     result.Data,
+		MimeType: result.MimeType,
+		Effect:   processor.Name(),
+		Params:   task.Params,
+	}
+	processedKey, err := w.fileService.UploadProcessedFile(taskCtx, task, out)
+	if err != nil {
+		return w.handleFailure(ctx, task, "upload failed", err)
 	}
 
-	processedKey, err := w.fileService.UploadProcessedFile(
+	artifacts, err := w.uploadArtifacts(
 		ctx,
+		taskCtx,
 		task,
-		processedData,
+		"",
+		out,
+		result.Artifacts,
 	)
 	if err != nil {
-		return w.taskService.SetTaskFailed(
-			ctx,
-			task.ID,
-			fmt.Sprintf("upload failed: %v", err),
-		)
+		err = w.handleFailure(ctx, task, "artifact upload failed", err)
+		// повторная попытка перезапишет те же ключи; в остальных случаях
+		// основной результат и часть файлов остались бы без задачи
+		if !rabbitmq.IsRetryable(err) {
+			keys := append([]string{processedKey}, artifactKeys(artifacts)...)
+			w.discardOutput(ctx, task.ID, keys...)
+		}
+		return err
 	}
 
 	downloadURL, genErr := w.fileService.GenerateDownloadURL(
@@ -100,15 +192,337 @@ func (w *ProcessingService) processTask(
 	if err := w.taskService.SetTaskCompleted(
 		ctx,
 		task.ID,
+		task.Run,
 		processedKey,
 		downloadURL,
+		artifacts,
+		false,
 	); err != nil {
+		if errors.Is(err, ErrTaskCancelled) {
+			keys := append([]string{processedKey}, artifactKeys(artifacts)...)
+			w.discardOutput(ctx, task.ID, keys...)
+			return nil
+		}
 		return err
 	}
 
+	// в кэш только после завершения: результат отменённой задачи удаляется
+	w.cache.Store(ctx, contentHash, task.Params, models.CachedResult{
+		ProcessedKey: processedKey,
+		MimeType:     result.MimeType,
+		Size:         int64(len(result.Data)),
+		Artifacts:    artifacts,
+		TaskID:       task.ID,
+	})
+
 	slog.Info("file processed successfully",
 		"task_id", task.ID,
+		"effect", processor.Name(),
+		"mime", result.MimeType,
 		"input_key", task.S3FileInfo.FileKey,
 		"output_key", processedKey)
 	return nil
 }
+
+// completeFromCache - завершение задачи готовым результатом другой
+// задачи. Объект принадлежит ей, поэтому при отмене он не удаляется
+func (w *ProcessingService) completeFromCache(
+	ctx context.Context,
+	task *models.S3FileTask,
+	cached *models.CachedResult,
+) error {
+	err := w.taskService.SetTaskCompleted(
+		ctx,
+		task.ID,
+		task.Run,
+		cached.ProcessedKey,
+		w.cache.DownloadURL(ctx, cached.ProcessedKey),
+		w.cache.ArtifactURLs(ctx, cached.Artifacts),
+		true,
+	)
+	if errors.Is(err, ErrTaskCancelled) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	slog.Info("file task completed from result cache",
+		"task_id", task.ID,
+		"output_key", cached.ProcessedKey,
+		"source_task_id", cached.TaskID)
+	return nil
+}
+
+// runProcessor - запуск эффекта с ограничением по времени; паника
+// процессора превращается в ошибку со стеком вместо падения воркера
+func (w *ProcessingService) runProcessor(
+	ctx context.Context,
+	processor processors.Processor,
+	input []byte,
+	params models.ProcessingParams,
+) (result *processors.Result, err error) {
+	timeout := time.Duration(w.cfg.TaskTimeoutSec) * time.Second
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("processor %q panicked: %v\n%s",
+				processor.Name(), r, debug.Stack())
+		}
+	}()
+
+	result, err = processor.Process(ctx, input, params)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("timed out after %s: %w", timeout, err)
+	}
+	return result, err
+}
+
+// uploadArtifacts - дополнительные файлы результата со ссылками на
+// скачивание. При ошибке возвращаются уже загруженные, чтобы их можно
+// было удалить
+func (w *ProcessingService) uploadArtifacts(
+	ctx, taskCtx context.Context,
+	task *models.S3FileTask,
+	variant string,
+	out ProcessedFile,
+	artifacts []ut.Artifact,
+) ([]models.TaskArtifact, error) {
+	if len(artifacts) == 0 {
+		return nil, nil
+	}
+
+	uploaded, err := w.fileService.UploadArtifacts(
+		taskCtx,
+		task,
+		variant,
+		out,
+		artifacts,
+	)
+	if err != nil {
+		return uploaded, err
+	}
+
+	for i := range uploaded {
+		uploaded[i].DownloadURL, err = w.fileService.GenerateDownloadURL(
+			ctx,
+			uploaded[i].Key,
+			1*time.Hour,
+		)
+		if err != nil {
+			slog.Error("failed to generate download URL",
+				"task_id", task.ID,
+				"key", uploaded[i].Key,
+				"error", err)
+		}
+	}
+	return uploaded, nil
+}
+
+func artifactKeys(artifacts []models.TaskArtifact) []string {
+	keys := make([]string, len(artifacts))
+	for i, artifact := range artifacts {
+		keys[i] = artifact.Key
+	}
+	return keys
+}
+
+// progressReporter - запись прогресса в Redis не чаще ProgressIntervalMs;
+// последний шаг записывается всегда
+func (w *ProcessingService) progressReporter(
+	ctx context.Context,
+	task *models.S3FileTask,
+) ut.ProgressFunc {
+	interval := time.Duration(w.cfg.ProgressIntervalMs) * time.Millisecond
+	started := time.Now()
+	var last time.Time
+
+	return func(done, total int, score float64) {
+		now := time.Now()
+		if done < total && now.Sub(last) < interval {
+			return
+		}
+		last = now
+
+		progress := models.TaskProgress{
+			Done:      done,
+			Total:     total,
+			Score:     score,
+			UpdatedAt: now,
+		}
+		if total > 0 {
+			progress.Percent = float64(done) * 100 / float64(total)
+		}
+		if done > 0 {
+			elapsed := now.Sub(started).Seconds()
+			progress.ETASeconds = elapsed / float64(done) * float64(total-done)
+		}
+
+		if err := w.taskService.SetTaskProgress(
+			ctx,
+			task.ID,
+			task.Run,
+			progress,
+		); err != nil && !errors.Is(err, ErrTaskCancelled) {
+			slog.Warn("failed to report task progress",
+				"task_id", task.ID,
+				"error", err)
+		}
+	}
+}
+
+// handleFailure - временные ошибки хранилища повторяются, пока не
+// исчерпаны попытки; остальные сразу переводят задачу в failed
+func (w *ProcessingService) handleFailure(
+	ctx context.Context,
+	task *models.S3FileTask,
+	stage string,
+	err error,
+) error {
+	if !isTransientError(err) ||
+		task.Attempts >= w.rabbitmqConsumer.MaxAttempts() {
+		return w.failTask(ctx, task, stage, err)
+	}
+
+	retryAt := time.Now().Add(w.rabbitmqConsumer.RetryDelay(task.Attempts))
+	msg := fmt.Sprintf("%s (attempt %d of %d): %v",
+		stage, task.Attempts, w.rabbitmqConsumer.MaxAttempts(), err)
+
+	setErr := w.taskService.SetTaskRetrying(
+		ctx,
+		task.ID,
+		task.Run,
+		truncateError(msg, w.cfg.MaxErrorLength),
+		retryAt,
+	)
+	if errors.Is(setErr, ErrTaskCancelled) {
+		slog.Info("task cancelled during processing", "task_id", task.ID)
+		return ErrTaskCancelled
+	}
+	if setErr != nil {
+		slog.Error("failed to record task retry",
+			"task_id", task.ID,
+			"error", setErr)
+	}
+
+	return rabbitmq.Retryable(fmt.Errorf("%s: %w", stage, err))
+}
+
+// failTask - перевод задачи в failed; возвращаемая ошибка отправляет
+// сообщение в dead-letter очередь. Если задачу уже отменили, возвращается
+// ErrTaskCancelled: вызывающий удаляет созданные результаты
+func (w *ProcessingService) failTask(
+	ctx context.Context,
+	task *models.S3FileTask,
+	stage string,
+	err error,
+) error {
+	msg := fmt.Sprintf("%s: %v", stage, err)
+	setErr := w.taskService.SetTaskFailed(
+		ctx,
+		task.ID,
+		task.Run,
+		truncateError(msg, w.cfg.MaxErrorLength),
+	)
+	if errors.Is(setErr, ErrTaskCancelled) {
+		// ошибка - следствие отмены, задача уже в статусе cancelled
+		slog.Info("task cancelled during processing", "task_id", task.ID)
+		return ErrTaskCancelled
+	}
+	if setErr != nil {
+		return errors.Join(fmt.Errorf("%s: %w", stage, err), setErr)
+	}
+	return fmt.Errorf("%s: %w", stage, err)
+}
+
+// isTransientError - сетевые сбои, таймауты, троттлинг и 5xx хранилища.
+// Отмена задачи и ошибки вроде NoSuchKey считаются постоянными
+func isTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		// обрыв соединения при чтении тела объекта
+		return true
+	}
+	return retry.IsErrorRetryables(retry.DefaultRetryables).
+		IsErrorRetryable(err) == aws.TrueTernary
+}
+
+// watchCancellation - контекст, отменяемый по событию cancelled задачи
+// или по началу её нового запуска
+func (w *ProcessingService) watchCancellation(
+	ctx context.Context,
+	task *models.S3FileTask,
+) (context.Context, func()) {
+	taskID := task.ID
+	taskCtx, cancel := context.WithCancelCause(ctx)
+
+	events, closeEvents, err := w.taskService.SubscribeTaskEvents(
+		taskCtx,
+		taskID,
+	)
+	if err != nil {
+		slog.Warn("task cancellation watch unavailable",
+			"task_id", taskID,
+			"error", err)
+		return taskCtx, func() { cancel(nil) }
+	}
+
+	go func() {
+		for event := range events {
+			if event.Task != nil &&
+				(event.Task.Status == models.TaskStatusCancelled ||
+					event.Task.Run != task.Run) {
+				slog.Info("aborting cancelled task", "task_id", taskID)
+				cancel(ErrTaskCancelled)
+				return
+			}
+		}
+	}()
+
+	return taskCtx, func() {
+		closeEvents()
+		cancel(nil)
+	}
+}
+
+// discardOutput - удаление результатов задачи, отменённой после их
+// загрузки, или незавершённого результата
+func (w *ProcessingService) discardOutput(
+	ctx context.Context,
+	taskID string,
+	keys ...string,
+) {
+	for _, key := range keys {
+		slog.Info("discarding task output",
+			"task_id", taskID,
+			"key", key)
+
+		if err := w.fileService.DeleteFile(ctx, key); err != nil {
+			slog.Error("failed to delete task output",
+				"task_id", taskID,
+				"key", key,
+				"error", err)
+		}
+	}
+}
+
+// truncateError - ограничение длины текста ошибки, сохраняемого в задаче
+func truncateError(msg string, limit int) string {
+	const suffix = "... (truncated)"
+	if limit <= 0 || len(msg) <= limit {
+		return msg
+	}
+
+	cut := max(limit-len(suffix), 0)
+	for cut > 0 && !utf8.RuneStart(msg[cut]) {
+		cut--
+	}
+	return msg[:cut] + suffix
+}
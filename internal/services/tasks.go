@@ -2,37 +2,70 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/BagRoman01/image-sketch-processor/internal/logging"
 	"github.com/BagRoman01/image-sketch-processor/internal/messaging/rabbitmq"
 	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/BagRoman01/image-sketch-processor/internal/processors"
 	"github.com/BagRoman01/image-sketch-processor/internal/repositories"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
 	"github.com/oklog/ulid/v2"
 )
 
+var (
+	ErrTaskNotFound       = repositories.ErrTaskNotFound
+	ErrTaskCancelled      = errors.New("task cancelled")
+	ErrTaskNotCancellable = errors.New("task already finished")
+	ErrTaskNotRetryable   = errors.New("task cannot be retried")
+)
+
 type TaskService struct {
 	redisRepo         *repositories.RedisRepository
 	rabbitmqPublisher *rabbitmq.RabbitMQPublisher
+	webhooks          *WebhookService
+	processors        *processors.Registry
+	cache             *ResultCache
 }
 
 func NewTaskService(
 	redisRepo *repositories.RedisRepository,
 	rabbitmqPublisher *rabbitmq.RabbitMQPublisher,
+	webhooks *WebhookService,
+	processors *processors.Registry,
+	cache *ResultCache,
 ) *TaskService {
 	return &TaskService{
 		redisRepo:         redisRepo,
 		rabbitmqPublisher: rabbitmqPublisher,
+		webhooks:          webhooks,
+		processors:        processors,
+		cache:             cache,
 	}
 }
 
 func (s *TaskService) CreateFileProcessingTask(
 	ctx context.Context,
 	fileInfo models.S3FileInfo,
+	opts models.TaskOptions,
 ) (*models.S3FileTask, error) {
 	logger := logging.LoggerFromContext(ctx)
 
+	if err := ValidateCallback(opts.Callback); err != nil {
+		return nil, err
+	}
+	if err := ut.ValidateVariants(opts.Variants); err != nil {
+		return nil, err
+	}
+	taskVariants := newTaskVariants(opts.Variants)
+	if err := s.validateParams(opts.Params, taskVariants); err != nil {
+		return nil, err
+	}
+
 	taskID := ulid.Make().String()
 
 	task := &models.S3FileTask{
@@ -43,6 +76,17 @@ func (s *TaskService) CreateFileProcessingTask(
 			UpdatedAt: time.Now(),
 		},
 		S3FileInfo: fileInfo,
+		BatchID:    opts.BatchID,
+		NoCache:    opts.NoCache,
+		Params:     opts.Params,
+		Variants:   taskVariants,
+		Callback:   opts.Callback,
+	}
+
+	cached := s.applyCachedResults(ctx, task)
+	if cached {
+		task.Status = models.TaskStatusCompleted
+		task.CompletedAt = time.Now()
 	}
 
 	if err := s.redisRepo.SaveTask(ctx, task); err != nil {
@@ -59,6 +103,14 @@ func (s *TaskService) CreateFileProcessingTask(
 		"task_id", taskID,
 	)
 
+	if cached {
+		logger.Info("task completed from result cache",
+			"task_id", taskID,
+			"file_key", fileInfo.FileKey)
+		s.notifyCallback(ctx, task)
+		return task, nil
+	}
+
 	if err := s.rabbitmqPublisher.PublishTask(ctx, task); err != nil {
 		logger.Error(
 			"failed to publish task to RabbitMQ",
@@ -72,25 +124,129 @@ func (s *TaskService) CreateFileProcessingTask(
 		"file processing task created",
 		"task_id", taskID,
 		"file_key", fileInfo.FileKey,
+		"style", opts.Params.Style,
 	)
 
 	return task, nil
 }
 
+// applyCachedResults - подстановка готовых результатов из кэша;
+// true, если готово всё и задачу не нужно отправлять воркеру
+func (s *TaskService) applyCachedResults(
+	ctx context.Context,
+	task *models.S3FileTask,
+) bool {
+	contentHash := task.S3FileInfo.SHA256
+	if task.NoCache || contentHash == "" {
+		return false
+	}
+
+	if len(task.Variants) == 0 {
+		cached := s.cache.Lookup(ctx, contentHash, task.Params)
+		if cached == nil {
+			return false
+		}
+		task.ProcessedKey = cached.ProcessedKey
+		task.DownloadURL = s.cache.DownloadURL(ctx, cached.ProcessedKey)
+		task.Artifacts = s.cache.ArtifactURLs(ctx, cached.Artifacts)
+		task.CacheHit = true
+		return true
+	}
+
+	hits := 0
+	for i := range task.Variants {
+		variant := &task.Variants[i]
+		params := task.Params.Merge(variant.Params)
+		cached := s.cache.Lookup(ctx, contentHash, params)
+		if cached == nil {
+			continue
+		}
+		applyCachedVariant(
+			variant,
+			cached,
+			s.cache.DownloadURL(ctx, cached.ProcessedKey),
+			s.cache.ArtifactURLs(ctx, cached.Artifacts),
+		)
+		hits++
+	}
+
+	task.CacheHit = hits == len(task.Variants)
+	return task.CacheHit
+}
+
+// newTaskVariants - варианты задачи из запроса; безымянные получают
+// имя по порядковому номеру, пропуская уже занятые явными именами:
+// имя входит в ключ S3 и должно быть уникальным
+func newTaskVariants(specs []models.VariantSpec) []models.TaskVariant {
+	if len(specs) == 0 {
+		return nil
+	}
+
+	taken := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		if spec.Name != "" {
+			taken[spec.Name] = true
+		}
+	}
+
+	variants := make([]models.TaskVariant, len(specs))
+	for i, spec := range specs {
+		name := spec.Name
+		for n := i + 1; name == ""; n++ {
+			if candidate := fmt.Sprintf("variant-%d", n); !taken[candidate] {
+				name = candidate
+				taken[name] = true
+			}
+		}
+		variants[i] = models.TaskVariant{
+			Name:   name,
+			Params: spec.ProcessingParams,
+			Status: models.TaskStatusPending,
+		}
+	}
+	return variants
+}
+
+// validateParams - основные параметры и каждый вариант поверх них
+func (s *TaskService) validateParams(
+	params models.ProcessingParams,
+	variants []models.TaskVariant,
+) error {
+	if err := s.processors.Validate(params); err != nil {
+		return err
+	}
+	for _, v := range variants {
+		if err := s.processors.Validate(params.Merge(v.Params)); err != nil {
+			return fmt.Errorf("variant %q: %w", v.Name, err)
+		}
+	}
+	return nil
+}
+
+// SetTaskProcessing - начало попытки; возвращает актуальную задачу
+// (в ней видны варианты, готовые после прошлых попыток)
 func (s *TaskService) SetTaskProcessing(
 	ctx context.Context,
 	taskID string,
-) error {
+	run, attempt int,
+) (*models.S3FileTask, error) {
 	logger := logging.LoggerFromContext(ctx)
 
-	if err := s.redisRepo.UpdateTask(
+	updated, err := s.redisRepo.UpdateTask(
 		ctx,
 		taskID,
 		func(task *models.S3FileTask) error {
+			if err := checkRun(task, run); err != nil {
+				return err
+			}
 			task.Status = models.TaskStatusProcessing
+			task.Attempts = attempt
+			task.NextRetryAt = nil
+			task.Progress = nil
 			task.UpdatedAt = time.Now()
 			return nil
-		}); err != nil {
+		})
+	if err != nil {
 		logger.Error(
 			"failed to set task processing",
 			"task_id",
@@ -98,29 +254,120 @@ func (s *TaskService) SetTaskProcessing(
 			"error",
 			err,
 		)
-		return fmt.Errorf("set task %q to processing: %w", taskID, err)
+		return nil, fmt.Errorf("set task %q to processing: %w", taskID, err)
 	}
 
+	s.publishEvent(ctx, models.TaskEventStatus, updated)
+	return updated, nil
+}
+
+func (s *TaskService) SetTaskProgress(
+	ctx context.Context,
+	taskID string,
+	run int,
+	progress models.TaskProgress,
+) error {
+	logger := logging.LoggerFromContext(ctx)
+
+	updated, err := s.redisRepo.UpdateTask(
+		ctx,
+		taskID,
+		func(task *models.S3FileTask) error {
+			if err := checkRun(task, run); err != nil {
+				return err
+			}
+			task.Progress = &progress
+			task.UpdatedAt = time.Now()
+			return nil
+		})
+	if err != nil {
+		logger.Error("failed to set task progress",
+			"task_id", taskID,
+			"done", progress.Done,
+			"error", err,
+		)
+		return fmt.Errorf("set task %q progress: %w", taskID, err)
+	}
+
+	s.publishEvent(ctx, models.TaskEventProgress, updated)
 	return nil
 }
 
+// SetVariantResult - запись итога одного варианта задачи
+func (s *TaskService) SetVariantResult(
+	ctx context.Context,
+	taskID string,
+	run int,
+	variant models.TaskVariant,
+) error {
+	logger := logging.LoggerFromContext(ctx)
+
+	updated, err := s.redisRepo.UpdateTask(
+		ctx,
+		taskID,
+		func(task *models.S3FileTask) error {
+			if err := checkRun(task, run); err != nil {
+				return err
+			}
+			i := slices.IndexFunc(task.Variants, func(v models.TaskVariant) bool {
+				return v.Name == variant.Name
+			})
+			if i < 0 {
+				return fmt.Errorf("variant %q not found", variant.Name)
+			}
+			task.Variants[i] = variant
+			task.UpdatedAt = time.Now()
+			return nil
+		})
+	if err != nil {
+		logger.Error("failed to set variant result",
+			"task_id", taskID,
+			"variant", variant.Name,
+			"error", err,
+		)
+		return fmt.Errorf(
+			"set task %q variant %q: %w",
+			taskID,
+			variant.Name,
+			err,
+		)
+	}
+
+	s.publishEvent(ctx, models.TaskEventProgress, updated)
+	return nil
+}
+
+// SetTaskCompleted - успешное завершение; cacheHit - результат взят из
+// кэша, а не обработан в этом запуске
 func (s *TaskService) SetTaskCompleted(
 	ctx context.Context,
-	taskID, processedKey, downloadURL string,
+	taskID string,
+	run int,
+	processedKey, downloadURL string,
+	artifacts []models.TaskArtifact,
+	cacheHit bool,
 ) error {
 	logger := logging.LoggerFromContext(ctx)
 
-	if err := s.redisRepo.UpdateTask(
+	updated, err := s.redisRepo.UpdateTask(
 		ctx,
 		taskID,
 		func(task *models.S3FileTask) error {
+			if err := checkRun(task, run); err != nil {
+				return err
+			}
 			task.Status = models.TaskStatusCompleted
 			task.ProcessedKey = processedKey
 			task.DownloadURL = downloadURL
+			task.Artifacts = artifacts
+			task.CacheHit = cacheHit
+			task.Error = ""
+			task.NextRetryAt = nil
 			task.CompletedAt = time.Now()
 			task.UpdatedAt = time.Now()
 			return nil
-		}); err != nil {
+		})
+	if err != nil {
 		logger.Error("failed to set task completed",
 			"task_id", taskID,
 			"processed_key", processedKey,
@@ -129,12 +376,53 @@ func (s *TaskService) SetTaskCompleted(
 		return fmt.Errorf("set task %q completed: %w", taskID, err)
 	}
 
+	s.publishEvent(ctx, models.TaskEventStatus, updated)
+	s.notifyCallback(ctx, updated)
+	return nil
+}
+
+// SetTaskRetrying - возврат задачи в pending после временной ошибки;
+// сообщение будет повторно доставлено воркеру в retryAt
+func (s *TaskService) SetTaskRetrying(
+	ctx context.Context,
+	taskID string,
+	run int,
+	errorMsg string,
+	retryAt time.Time,
+) error {
+	logger := logging.LoggerFromContext(ctx)
+
+	updated, err := s.redisRepo.UpdateTask(
+		ctx,
+		taskID,
+		func(task *models.S3FileTask) error {
+			if err := checkRun(task, run); err != nil {
+				return err
+			}
+			task.Status = models.TaskStatusPending
+			task.Error = errorMsg
+			task.NextRetryAt = &retryAt
+			task.UpdatedAt = time.Now()
+			return nil
+		})
+	if err != nil {
+		logger.Error("failed to set task retrying",
+			"task_id", taskID,
+			"error_msg", errorMsg,
+			"update_error", err,
+		)
+		return fmt.Errorf("set task %q retrying: %w", taskID, err)
+	}
+
+	s.publishEvent(ctx, models.TaskEventStatus, updated)
 	return nil
 }
 
 func (s *TaskService) SetTaskFailed(
 	ctx context.Context,
-	taskID, errorMsg string,
+	taskID string,
+	run int,
+	errorMsg string,
 ) error {
 	logger := logging.LoggerFromContext(ctx)
 	logger.Warn("setting task to failed",
@@ -142,15 +430,20 @@ func (s *TaskService) SetTaskFailed(
 		"error", errorMsg,
 	)
 
-	if err := s.redisRepo.UpdateTask(
+	updated, err := s.redisRepo.UpdateTask(
 		ctx,
 		taskID,
 		func(task *models.S3FileTask) error {
+			if err := checkRun(task, run); err != nil {
+				return err
+			}
 			task.Status = models.TaskStatusFailed
 			task.Error = errorMsg
+			task.NextRetryAt = nil
 			task.UpdatedAt = time.Now()
 			return nil
-		}); err != nil {
+		})
+	if err != nil {
 		logger.Error("failed to set task failed",
 			"task_id", taskID,
 			"error_msg", errorMsg,
@@ -159,6 +452,25 @@ func (s *TaskService) SetTaskFailed(
 		return fmt.Errorf("set task %q failed: %w", taskID, err)
 	}
 
+	s.publishEvent(ctx, models.TaskEventStatus, updated)
+	s.notifyCallback(ctx, updated)
+	return nil
+}
+
+// checkRun - запись от воркера допустима, только если задача не отменена
+// и сообщение относится к её текущему запуску (см. RetryTask)
+func checkRun(task *models.S3FileTask, run int) error {
+	if task.Status == models.TaskStatusCancelled {
+		return ErrTaskCancelled
+	}
+	if task.Run != run {
+		return fmt.Errorf(
+			"%w: run %d superseded by run %d",
+			ErrTaskCancelled,
+			run,
+			task.Run,
+		)
+	}
 	return nil
 }
 
@@ -168,3 +480,284 @@ func (s *TaskService) GetTask(
 ) (*models.S3FileTask, error) {
 	return s.redisRepo.GetTask(ctx, taskID)
 }
+
+// CancelTask - отмена задачи: ожидающая будет пропущена воркером,
+// выполняющаяся прервана по событию отмены
+func (s *TaskService) CancelTask(
+	ctx context.Context,
+	taskID string,
+) (*models.S3FileTask, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	updated, err := s.redisRepo.UpdateTask(
+		ctx,
+		taskID,
+		func(task *models.S3FileTask) error {
+			if task.Status.IsFinal() {
+				return fmt.Errorf(
+					"%w: status is %s",
+					ErrTaskNotCancellable,
+					task.Status,
+				)
+			}
+			task.Status = models.TaskStatusCancelled
+			task.UpdatedAt = time.Now()
+			return nil
+		})
+	if err != nil {
+		logger.Warn("failed to cancel task",
+			"task_id", taskID,
+			"error", err,
+		)
+		return nil, fmt.Errorf("cancel task %q: %w", taskID, err)
+	}
+
+	logger.Info("task cancelled", "task_id", taskID)
+
+	s.publishEvent(ctx, models.TaskEventStatus, updated)
+	return updated, nil
+}
+
+// RetryTask - повторный запуск задачи в статусе failed или cancelled,
+// при необходимости с новыми параметрами
+func (s *TaskService) RetryTask(
+	ctx context.Context,
+	taskID string,
+	params *models.ProcessingParams,
+) (*models.S3FileTask, error) {
+	return s.requeueTask(ctx, taskID, params, func(task *models.S3FileTask) error {
+		if task.Status != models.TaskStatusFailed &&
+			task.Status != models.TaskStatusCancelled {
+			return fmt.Errorf(
+				"%w: status is %s",
+				ErrTaskNotRetryable,
+				task.Status,
+			)
+		}
+		if params != nil {
+			return s.validateParams(*params, task.Variants)
+		}
+		return nil
+	})
+}
+
+// RequeueTask - возврат в очередь задачи, сообщение которой попало в
+// dead-letter очередь; выполненные, выполняющиеся и отменённые задачи не
+// трогаются: отменённую перезапускает только сам клиент через RetryTask
+func (s *TaskService) RequeueTask(
+	ctx context.Context,
+	taskID string,
+) (*models.S3FileTask, error) {
+	return s.requeueTask(ctx, taskID, nil, func(task *models.S3FileTask) error {
+		if task.Status == models.TaskStatusCompleted ||
+			task.Status == models.TaskStatusProcessing ||
+			task.Status == models.TaskStatusCancelled {
+			return fmt.Errorf(
+				"%w: status is %s",
+				ErrTaskNotRetryable,
+				task.Status,
+			)
+		}
+		return nil
+	})
+}
+
+// requeueTask - перенос текущего запуска в историю, сброс задачи в pending
+// и публикация в очередь. Новый номер запуска отсекает старые сообщения
+// и воркеры, ещё работающие над прошлым запуском
+func (s *TaskService) requeueTask(
+	ctx context.Context,
+	taskID string,
+	params *models.ProcessingParams,
+	check func(task *models.S3FileTask) error,
+) (*models.S3FileTask, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	// previous - состояние до переноса, восстанавливается, если
+	// сообщение не удалось опубликовать
+	var previous models.S3FileTask
+	updated, err := s.redisRepo.UpdateTask(
+		ctx,
+		taskID,
+		func(task *models.S3FileTask) error {
+			if err := check(task); err != nil {
+				return err
+			}
+
+			previous = *task
+			previous.History = slices.Clone(task.History)
+			previous.Variants = slices.Clone(task.Variants)
+
+			task.History = append(task.History, models.TaskRun{
+				Run:        task.Run,
+				Status:     task.Status,
+				Attempts:   task.Attempts,
+				Error:      task.Error,
+				Params:     task.Params,
+				FinishedAt: task.UpdatedAt,
+			})
+
+			if params != nil {
+				task.Params = *params
+			}
+			task.Run++
+			task.Status = models.TaskStatusPending
+			task.Error = ""
+			task.Attempts = 0
+			task.NextRetryAt = nil
+			task.Progress = nil
+			task.ProcessedKey = ""
+			task.DownloadURL = ""
+			task.Artifacts = nil
+			task.CacheHit = false
+			for i := range task.Variants {
+				task.Variants[i] = models.TaskVariant{
+					Name:   task.Variants[i].Name,
+					Params: task.Variants[i].Params,
+					Status: models.TaskStatusPending,
+				}
+			}
+			task.CompletedAt = time.Time{}
+			task.UpdatedAt = time.Now()
+			return nil
+		})
+	if err != nil {
+		logger.Warn("failed to requeue task",
+			"task_id", taskID,
+			"error", err,
+		)
+		return nil, fmt.Errorf("requeue task %q: %w", taskID, err)
+	}
+
+	if err := s.rabbitmqPublisher.PublishTask(ctx, updated); err != nil {
+		s.rollbackRequeue(ctx, updated.Run, &previous)
+		return nil, fmt.Errorf("requeue task %q: %w", taskID, err)
+	}
+
+	logger.Info("task requeued",
+		"task_id", taskID,
+		"run", updated.Run,
+		"style", updated.Params.Style,
+	)
+
+	s.publishEvent(ctx, models.TaskEventStatus, updated)
+	return updated, nil
+}
+
+// rollbackRequeue - возврат задачи в состояние до requeueTask: без
+// сообщения в очереди pending-задача зависла бы, а повторить её нельзя
+func (s *TaskService) rollbackRequeue(
+	ctx context.Context,
+	run int,
+	previous *models.S3FileTask,
+) {
+	_, err := s.redisRepo.UpdateTask(
+		ctx,
+		previous.ID,
+		func(task *models.S3FileTask) error {
+			// задачу успели отменить или перезапустить - не трогаем
+			if err := checkRun(task, run); err != nil {
+				return err
+			}
+			*task = *previous
+			task.UpdatedAt = time.Now()
+			return nil
+		})
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error("failed to roll back requeue",
+			"task_id", previous.ID,
+			"run", run,
+			"error", err,
+		)
+	}
+}
+
+// notifyCallback - вебхук по завершении задачи; попытки сохраняются в задаче
+func (s *TaskService) notifyCallback(
+	ctx context.Context,
+	task *models.S3FileTask,
+) {
+	if s.webhooks == nil || task.Callback == nil {
+		return
+	}
+
+	s.webhooks.DeliverAsync(ctx, task, func(attempt models.CallbackAttempt) {
+		if _, err := s.redisRepo.UpdateTask(
+			context.WithoutCancel(ctx),
+			task.ID,
+			func(t *models.S3FileTask) error {
+				t.CallbackAttempts = append(t.CallbackAttempts, attempt)
+				return nil
+			},
+		); err != nil {
+			logging.LoggerFromContext(ctx).Warn(
+				"failed to record callback attempt",
+				"task_id", task.ID,
+				"attempt", attempt.Attempt,
+				"error", err,
+			)
+		}
+	})
+}
+
+// SubscribeTaskEvents - поток изменений задачи для SSE
+func (s *TaskService) SubscribeTaskEvents(
+	ctx context.Context,
+	taskID string,
+) (<-chan models.TaskEvent, func() error, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	payloads, closeFn, err := s.redisRepo.SubscribeTaskEvents(ctx, taskID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("subscribe to task %q events: %w", taskID, err)
+	}
+
+	events := make(chan models.TaskEvent)
+	go func() {
+		defer close(events)
+		for payload := range payloads {
+			var event models.TaskEvent
+			if err := json.Unmarshal(payload, &event); err != nil {
+				logger.Warn("skipping malformed task event",
+					"task_id", taskID,
+					"error", err)
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, closeFn, nil
+}
+
+// publishEvent - рассылка изменения подписчикам; ошибки не прерывают
+// обработку, клиент всегда может перечитать задачу через GET
+func (s *TaskService) publishEvent(
+	ctx context.Context,
+	eventType models.TaskEventType,
+	task *models.S3FileTask,
+) {
+	logger := logging.LoggerFromContext(ctx)
+
+	payload, err := json.Marshal(models.TaskEvent{
+		Type: eventType,
+		Task: task.Redacted(),
+	})
+	if err != nil {
+		logger.Error("failed to marshal task event",
+			"task_id", task.ID,
+			"error", err)
+		return
+	}
+
+	if err := s.redisRepo.PublishTaskEvent(ctx, task.ID, payload); err != nil {
+		logger.Warn("failed to publish task event",
+			"task_id", task.ID,
+			"type", eventType,
+			"error", err)
+	}
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	"github.com/BagRoman01/image-sketch-processor/internal/repositories"
+)
+
+var (
+	ErrUploadSessionNotFound = repositories.ErrUploadSessionNotFound
+	// ErrInvalidPart - номер или размер части не соответствует сессии
+	ErrInvalidPart = errors.New("invalid upload part")
+	// ErrUploadIncomplete - не все части загружены
+	ErrUploadIncomplete = errors.New("upload is incomplete")
+)
+
+// maxUploadParts - ограничение S3 на число частей
+const maxUploadParts = 10000
+
+// uploadSweepInterval - как часто прерываются загрузки по частям,
+// сессии которых истекли
+const uploadSweepInterval = 1 * time.Hour
+
+// UploadSessionService - возобновляемые загрузки: клиент отправляет части
+// в любом порядке, после обрыва запрашивает сессию и досылает недостающие
+type UploadSessionService struct {
+	redisRepo   *repositories.RedisRepository
+	s3Repo      *repositories.S3Repository
+	fileService *FileService
+}
+
+func NewUploadSessionService(
+	redisRepo *repositories.RedisRepository,
+	s3Repo *repositories.S3Repository,
+	fileService *FileService,
+) *UploadSessionService {
+	return &UploadSessionService{
+		redisRepo:   redisRepo,
+		s3Repo:      s3Repo,
+		fileService: fileService,
+	}
+}
+
+func (s *UploadSessionService) CreateSession(
+	ctx context.Context,
+	req models.UploadSessionRequest,
+) (*models.UploadSessionResponse, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	contentType, err := imageContentType(req.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	if req.Size <= 0 {
+		return nil, fmt.Errorf("%w: size must be positive", ErrInvalidUpload)
+	}
+	if limit := s.s3Repo.MaxUploadSize(); limit > 0 && req.Size > limit {
+		return nil, fmt.Errorf(
+			"%w: %d bytes, limit is %d",
+			ErrFileTooLarge,
+			req.Size,
+			limit,
+		)
+	}
+
+	partSize := s.s3Repo.MultipartPartSize()
+	totalParts := int((req.Size + partSize - 1) / partSize)
+	if totalParts > maxUploadParts {
+		return nil, fmt.Errorf(
+			"%w: %d parts of %d bytes, S3 allows %d",
+			ErrFileTooLarge,
+			totalParts,
+			partSize,
+			maxUploadParts,
+		)
+	}
+
+	fileID := s.fileService.newFileID()
+	key := "upload/" + fileID
+
+	uploadID, err := s.s3Repo.CreateMultipartUpload(
+		ctx,
+		key,
+		contentType,
+		req.FileName,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &models.UploadSession{
+		ID:          fileID,
+		Key:         key,
+		S3UploadID:  uploadID,
+		FileName:    req.FileName,
+		ContentType: contentType,
+		Size:        req.Size,
+		PartSize:    partSize,
+		TotalParts:  totalParts,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(repositories.UploadSessionTTL),
+	}
+
+	if err := s.redisRepo.SaveUploadSession(ctx, session); err != nil {
+		s.s3Repo.AbortMultipartUpload(ctx, key, uploadID)
+		return nil, err
+	}
+
+	logger.Info("upload session created",
+		"session_id", session.ID,
+		"size", session.Size,
+		"parts", session.TotalParts)
+	return sessionResponse(session), nil
+}
+
+func (s *UploadSessionService) GetSession(
+	ctx context.Context,
+	sessionID string,
+) (*models.UploadSessionResponse, error) {
+	session, err := s.redisRepo.GetUploadSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return sessionResponse(session), nil
+}
+
+// UploadPart - часть number из body; размер должен совпадать с
+// ожидаемым, повторная отправка части заменяет прежнюю
+func (s *UploadSessionService) UploadPart(
+	ctx context.Context,
+	sessionID string,
+	number int,
+	body io.Reader,
+) (*models.UploadPart, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	session, err := s.redisRepo.GetUploadSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if number < 1 || number > session.TotalParts {
+		return nil, fmt.Errorf(
+			"%w: part number must be between 1 and %d",
+			ErrInvalidPart,
+			session.TotalParts,
+		)
+	}
+
+	expected := session.PartSizeOf(number)
+	data, err := io.ReadAll(io.LimitReader(body, expected+1))
+	if err != nil {
+		return nil, fmt.Errorf("read part %d: %w", number, err)
+	}
+	if int64(len(data)) != expected {
+		return nil, fmt.Errorf(
+			"%w: part %d must be %d bytes, got %d",
+			ErrInvalidPart,
+			number,
+			expected,
+			len(data),
+		)
+	}
+
+	etag, err := s.s3Repo.UploadPart(
+		ctx,
+		session.Key,
+		session.S3UploadID,
+		number,
+		data,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	part := models.UploadPart{
+		Number: number,
+		ETag:   etag,
+		Size:   int64(len(data)),
+	}
+	if err := s.redisRepo.SaveUploadPart(ctx, session, part); err != nil {
+		return nil, err
+	}
+
+	logger.Debug("upload part stored",
+		"session_id", sessionID,
+		"part", number,
+		"size", part.Size)
+	return &part, nil
+}
+
+// CompleteSession - сборка объекта из частей и создание задачи, как
+// после прямой загрузки (FinalizeUpload)
+func (s *UploadSessionService) CompleteSession(
+	ctx context.Context,
+	sessionID string,
+	opts models.TaskOptions,
+) (*models.S3FileTask, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	if err := s.fileService.ValidateOptions(opts); err != nil {
+		return nil, err
+	}
+
+	session, err := s.redisRepo.GetUploadSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if missing := missingParts(session); len(missing) > 0 {
+		return nil, fmt.Errorf(
+			"%w: %d of %d parts missing",
+			ErrUploadIncomplete,
+			len(missing),
+			session.TotalParts,
+		)
+	}
+
+	if err := s.s3Repo.CompleteMultipartUpload(
+		ctx,
+		session.Key,
+		session.S3UploadID,
+		session.Parts,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := s.redisRepo.DeleteUploadSession(ctx, sessionID); err != nil {
+		logger.Warn("failed to delete completed upload session",
+			"session_id", sessionID,
+			"error", err)
+	}
+
+	logger.Info("upload session completed",
+		"session_id", sessionID,
+		"size", session.Size)
+	return s.fileService.FinalizeUpload(ctx, session.ID, opts)
+}
+
+// AbortSession - отмена загрузки, принятые части удаляются из S3
+func (s *UploadSessionService) AbortSession(
+	ctx context.Context,
+	sessionID string,
+) error {
+	session, err := s.redisRepo.GetUploadSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.s3Repo.AbortMultipartUpload(
+		ctx,
+		session.Key,
+		session.S3UploadID,
+	); err != nil {
+		return err
+	}
+
+	return s.redisRepo.DeleteUploadSession(ctx, sessionID)
+}
+
+func sessionResponse(
+	session *models.UploadSession,
+) *models.UploadSessionResponse {
+	resp := &models.UploadSessionResponse{
+		UploadSession: *session,
+		MissingParts:  missingParts(session),
+	}
+	if resp.Parts == nil {
+		resp.Parts = []models.UploadPart{}
+	}
+	for _, part := range session.Parts {
+		resp.Received += part.Size
+	}
+	return resp
+}
+
+func missingParts(session *models.UploadSession) []int {
+	received := make(map[int]bool, len(session.Parts))
+	for _, part := range session.Parts {
+		received[part.Number] = true
+	}
+
+	missing := []int{}
+	for n := 1; n <= session.TotalParts; n++ {
+		if !received[n] {
+			missing = append(missing, n)
+		}
+	}
+	return missing
+}
+
+// RunSweeper - периодическая очистка брошенных загрузок до отмены ctx.
+// Сессия истекает в Redis, но загрузка в S3 остаётся вместе с частями,
+// за хранение которых бакет продолжает платить
+func (s *UploadSessionService) RunSweeper(ctx context.Context) {
+	ticker := time.NewTicker(uploadSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		s.AbortExpiredUploads(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// AbortExpiredUploads - прерывание загрузок по частям старше
+// UploadSessionTTL: их сессии уже истекли и завершить их нельзя.
+// Возвращает число прерванных загрузок
+func (s *UploadSessionService) AbortExpiredUploads(ctx context.Context) int {
+	logger := logging.LoggerFromContext(ctx)
+
+	uploads, err := s.s3Repo.ListMultipartUploads(ctx, "upload/")
+	if err != nil {
+		logger.Error("failed to list multipart uploads", "error", err)
+		return 0
+	}
+
+	expired := time.Now().Add(-repositories.UploadSessionTTL)
+	aborted := 0
+	for _, upload := range uploads {
+		if upload.Initiated.After(expired) {
+			continue
+		}
+		err := s.s3Repo.AbortMultipartUpload(ctx, upload.Key, upload.UploadID)
+		if err != nil {
+			// другой экземпляр API мог прервать её раньше
+			logger.Warn("failed to abort expired multipart upload",
+				"key", upload.Key,
+				"error", err)
+			continue
+		}
+		aborted++
+	}
+
+	if aborted > 0 {
+		logger.Info("expired multipart uploads aborted", "count", aborted)
+	}
+	return aborted
+}
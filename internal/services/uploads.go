@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
+)
+
+var (
+	ErrInvalidUpload = errors.New("invalid upload request")
+	// ErrUnsupportedMediaType - загружен или заявлен не image/*
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+)
+
+// PresignUpload - ссылка на загрузку в upload/<fileID> напрямую в S3,
+// минуя API; задача создаётся в FinalizeUpload
+func (s *FileService) PresignUpload(
+	ctx context.Context,
+	req models.PresignUploadRequest,
+) (*models.PresignedUpload, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	contentType, err := imageContentType(req.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSize := s.s3Repo.MaxUploadSize()
+	if req.Size < 0 {
+		return nil, fmt.Errorf("%w: size must be positive", ErrInvalidUpload)
+	}
+	if maxSize > 0 && req.Size > maxSize {
+		return nil, fmt.Errorf(
+			"%w: %d bytes, limit is %d",
+			ErrFileTooLarge,
+			req.Size,
+			maxSize,
+		)
+	}
+
+	fileID := s.newFileID()
+	key := "upload/" + fileID
+	upload := &models.PresignedUpload{
+		FileID:    fileID,
+		Key:       key,
+		Method:    req.Method,
+		MaxSize:   maxSize,
+		ExpiresAt: time.Now().Add(s.s3Repo.PresignExpiry()),
+	}
+
+	switch req.Method {
+	case "", models.UploadMethodPut:
+		if req.Size == 0 {
+			return nil, fmt.Errorf(
+				"%w: size is required for PUT",
+				ErrInvalidUpload,
+			)
+		}
+
+		presigned, err := s.s3Repo.PresignUploadPut(
+			ctx,
+			key,
+			contentType,
+			req.FileName,
+			req.Size,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		upload.Method = models.UploadMethodPut
+		upload.URL = presigned.URL
+		upload.Headers = signedHeaders(presigned.SignedHeader)
+	case models.UploadMethodPost:
+		presigned, err := s.s3Repo.PresignUploadPost(
+			ctx,
+			key,
+			contentType,
+			req.FileName,
+			maxSize,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		upload.URL = presigned.URL
+		upload.Fields = presigned.Values
+	default:
+		return nil, fmt.Errorf(
+			"%w: method must be put or post",
+			ErrInvalidUpload,
+		)
+	}
+
+	logger.Info("upload presigned",
+		"file_id", fileID,
+		"method", upload.Method,
+		"ct", contentType,
+		"size", req.Size)
+	return upload, nil
+}
+
+// signedHeaders - заголовки, вошедшие в подпись PUT (кроме Host, его
+// выставит HTTP-клиент)
+func signedHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		if strings.EqualFold(name, "Host") || len(values) == 0 {
+			continue
+		}
+		headers[name] = values[0]
+	}
+	return headers
+}
+
+// FinalizeUpload - проверка загруженного напрямую объекта (размер по
+// HeadObject, формат и разрешение по заголовку) и создание задачи.
+// Не прошедший проверку объект удаляется
+func (s *FileService) FinalizeUpload(
+	ctx context.Context,
+	fileID string,
+	opts models.TaskOptions,
+) (*models.S3FileTask, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	if err := s.ValidateOptions(opts); err != nil {
+		return nil, err
+	}
+
+	fileInfo, err := s.headUpload(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSize := s.s3Repo.MaxUploadSize()
+	if maxSize > 0 && fileInfo.Content.ContentLength > maxSize {
+		s.DeleteFile(ctx, fileInfo.FileKey)
+		return nil, fmt.Errorf(
+			"%w: %d bytes, limit is %d",
+			ErrFileTooLarge,
+			fileInfo.Content.ContentLength,
+			maxSize,
+		)
+	}
+	detected, err := s.inspectUpload(ctx, fileInfo.FileKey)
+	if err != nil {
+		return nil, err
+	}
+	// заявленный при подписи тип мог не совпасть с содержимым
+	fileInfo.Content.ContentType = detected.MimeType
+
+	task, err := s.taskService.CreateFileProcessingTask(ctx, *fileInfo, opts)
+	if err != nil {
+		logger.Error(
+			"failed to create processing task",
+			"error", err,
+			"key", fileInfo.FileKey,
+		)
+		return nil, err
+	}
+
+	logger.Info("direct upload finalized",
+		"file_id", fileID,
+		"task_id", task.ID,
+		"size", fileInfo.Content.ContentLength)
+	return task, nil
+}
+
+// inspectUpload - формат и размеры загруженного объекта по его
+// заголовку; объект, который не является допустимым изображением,
+// удаляется
+func (s *FileService) inspectUpload(
+	ctx context.Context,
+	key string,
+) (*ut.ImageInfo, error) {
+	body, _, err := s.OpenFile(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	detected, _, err := s.inspectSource(ctx, body, key)
+	if err != nil {
+		var invalid *ut.InvalidImageError
+		if errors.As(err, &invalid) {
+			s.DeleteFile(ctx, key)
+		}
+		return nil, err
+	}
+	return detected, nil
+}
+
+// imageContentType - нормализованный MIME-тип, только image/*
+func imageContentType(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "image/") {
+		return "", fmt.Errorf(
+			"%w: %q, expected image/*",
+			ErrUnsupportedMediaType,
+			contentType,
+		)
+	}
+	return mediaType, nil
+}
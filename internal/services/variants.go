@@ -0,0 +1,282 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"log/slog"
+	"time"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/messaging/rabbitmq"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
+)
+
+// processVariants - все варианты задачи из одного скачанного исходника.
+// Готовые после прошлых попыток варианты пропускаются, поэтому временная
+// ошибка загрузки повторяет только оставшиеся
+func (w *ProcessingService) processVariants(
+	ctx, taskCtx context.Context,
+	task *models.S3FileTask,
+	variants []models.TaskVariant,
+	input []byte,
+	contentHash string,
+) error {
+	report := w.progressReporter(ctx, task)
+
+	for i, variant := range variants {
+		if variant.Status == models.TaskStatusCompleted {
+			continue
+		}
+
+		stage := fmt.Sprintf("variant %q", variant.Name)
+		params := task.Params.Merge(variant.Params)
+
+		if !task.NoCache {
+			if cached := w.cache.Lookup(ctx, contentHash, params); cached != nil {
+				applyCachedVariant(
+					&variant,
+					cached,
+					w.cache.DownloadURL(ctx, cached.ProcessedKey),
+					w.cache.ArtifactURLs(ctx, cached.Artifacts),
+				)
+				if err := w.taskService.SetVariantResult(
+					ctx,
+					task.ID,
+					task.Run,
+					variant,
+				); err != nil {
+					if errors.Is(err, ErrTaskCancelled) {
+						w.discardOutput(ctx, task.ID, variantKeys(variants)...)
+						return nil
+					}
+					return err
+				}
+				variants[i] = variant
+				continue
+			}
+		}
+
+		processor, err := w.processors.Resolve(params)
+		if err != nil {
+			w.failVariant(ctx, task, variant, err)
+			return w.abortVariants(
+				ctx,
+				task,
+				variants,
+				w.failTask(ctx, task, stage+": invalid params", err),
+			)
+		}
+
+		result, err := w.runProcessor(
+			ut.WithProgress(taskCtx, variantProgress(report, i, len(variants))),
+			processor,
+			input,
+			params,
+		)
+		if err != nil {
+			w.failVariant(ctx, task, variant, err)
+			return w.abortVariants(
+				ctx,
+				task,
+				variants,
+				w.failTask(ctx, task, stage+": processing failed", err),
+			)
+		}
+
+		out := ProcessedFile{
+			Data:     result.Data,
+			MimeType: result.MimeType,
+			Effect:   processor.Name(),
+			Params:   params,
+		}
+		key, err := w.fileService.UploadVariantFile(
+			taskCtx,
+			task,
+			variant.Name,
+			out,
+		)
+		if err != nil {
+			return w.abortVariants(
+				ctx,
+				task,
+				variants,
+				w.handleFailure(ctx, task, stage+": upload failed", err),
+			)
+		}
+
+		artifacts, err := w.uploadArtifacts(
+			ctx,
+			taskCtx,
+			task,
+			variant.Name,
+			out,
+			result.Artifacts,
+		)
+		if err != nil {
+			err = w.handleFailure(
+				ctx,
+				task,
+				stage+": artifact upload failed",
+				err,
+			)
+			// незавершённый вариант не сохраняется, его файлы удаляются
+			// всегда, кроме повторной попытки, которая их перезапишет
+			current := append([]string{key}, artifactKeys(artifacts)...)
+			if !errors.Is(err, ErrTaskCancelled) && !rabbitmq.IsRetryable(err) {
+				w.discardOutput(ctx, task.ID, current...)
+			}
+			return w.abortVariants(ctx, task, variants, err, current...)
+		}
+
+		variant.Status = models.TaskStatusCompleted
+		variant.ProcessedKey = key
+		variant.MimeType = result.MimeType
+		variant.Size = int64(len(result.Data))
+		variant.Artifacts = artifacts
+		variant.Error = ""
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(result.Data)); err == nil {
+			variant.Width, variant.Height = cfg.Width, cfg.Height
+		}
+
+		url, err := w.fileService.GenerateDownloadURL(ctx, key, 1*time.Hour)
+		if err != nil {
+			slog.Error("failed to generate download URL",
+				"task_id", task.ID,
+				"variant", variant.Name,
+				"error", err)
+		}
+		variant.DownloadURL = url
+
+		if err := w.taskService.SetVariantResult(
+			ctx,
+			task.ID,
+			task.Run,
+			variant,
+		); err != nil {
+			if errors.Is(err, ErrTaskCancelled) {
+				variants[i] = variant
+				w.discardOutput(ctx, task.ID, variantKeys(variants)...)
+				return nil
+			}
+			return err
+		}
+		variants[i] = variant
+
+		slog.Info("task variant processed",
+			"task_id", task.ID,
+			"variant", variant.Name,
+			"effect", processor.Name(),
+			"output_key", key,
+			"size", variant.Size)
+	}
+
+	allCached := true
+	for _, variant := range variants {
+		allCached = allCached && variant.CacheHit
+	}
+
+	if err := w.taskService.SetTaskCompleted(
+		ctx,
+		task.ID,
+		task.Run,
+		"",
+		"",
+		nil,
+		allCached,
+	); err != nil {
+		if errors.Is(err, ErrTaskCancelled) {
+			w.discardOutput(ctx, task.ID, variantKeys(variants)...)
+			return nil
+		}
+		return err
+	}
+
+	// в кэш только после завершения: результаты отменённой задачи удаляются
+	for _, variant := range variants {
+		if variant.CacheHit {
+			continue
+		}
+		w.cache.Store(
+			ctx,
+			contentHash,
+			task.Params.Merge(variant.Params),
+			models.CachedResult{
+				ProcessedKey: variant.ProcessedKey,
+				MimeType:     variant.MimeType,
+				Size:         variant.Size,
+				Width:        variant.Width,
+				Height:       variant.Height,
+				Artifacts:    variant.Artifacts,
+				TaskID:       task.ID,
+			},
+		)
+	}
+
+	slog.Info("file processed successfully",
+		"task_id", task.ID,
+		"variants", len(variants),
+		"input_key", task.S3FileInfo.FileKey)
+	return nil
+}
+
+// abortVariants - досрочный выход из обработки вариантов с ошибкой err.
+// При отмене задачи удаляются готовые варианты и extra - уже загруженные
+// файлы текущего; при ошибке готовые остаются, их переиспользует RetryTask
+func (w *ProcessingService) abortVariants(
+	ctx context.Context,
+	task *models.S3FileTask,
+	variants []models.TaskVariant,
+	err error,
+	extra ...string,
+) error {
+	if errors.Is(err, ErrTaskCancelled) {
+		keys := append(variantKeys(variants), extra...)
+		w.discardOutput(ctx, task.ID, keys...)
+	}
+	return err
+}
+
+// failVariant - отметка варианта, на котором задача завершилась ошибкой
+func (w *ProcessingService) failVariant(
+	ctx context.Context,
+	task *models.S3FileTask,
+	variant models.TaskVariant,
+	cause error,
+) {
+	variant.Status = models.TaskStatusFailed
+	variant.Error = truncateError(cause.Error(), w.cfg.MaxErrorLength)
+
+	err := w.taskService.SetVariantResult(ctx, task.ID, task.Run, variant)
+	if err != nil && !errors.Is(err, ErrTaskCancelled) {
+		slog.Warn("failed to record variant failure",
+			"task_id", task.ID,
+			"variant", variant.Name,
+			"error", err)
+	}
+}
+
+// variantProgress - прогресс варианта i из n как доля хода всей задачи
+func variantProgress(report ut.ProgressFunc, i, n int) ut.ProgressFunc {
+	return func(done, total int, score float64) {
+		if total <= 0 {
+			return
+		}
+		report(i*total+done, n*total, score)
+	}
+}
+
+// variantKeys - результаты, созданные самой задачей (взятые из кэша
+// принадлежат другим задачам)
+func variantKeys(variants []models.TaskVariant) []string {
+	var keys []string
+	for _, v := range variants {
+		if v.ProcessedKey != "" && !v.CacheHit {
+			keys = append(keys, v.ProcessedKey)
+			keys = append(keys, artifactKeys(v.Artifacts)...)
+		}
+	}
+	return keys
+}
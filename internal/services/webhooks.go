@@ -0,0 +1,297 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/config"
+	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+	ut "github.com/BagRoman01/image-sketch-processor/internal/utils"
+)
+
+var (
+	ErrInvalidCallback = errors.New("invalid callback")
+	// ErrCallbackBlocked - callback_url ведёт во внутреннюю сеть
+	ErrCallbackBlocked = errors.New("callback address is not allowed")
+)
+
+const (
+	WebhookEventCompleted = "task.completed"
+	WebhookEventFailed    = "task.failed"
+
+	webhookEventHeader     = "X-Webhook-Event"
+	webhookTimestampHeader = "X-Webhook-Timestamp"
+	webhookAttemptHeader   = "X-Webhook-Attempt"
+)
+
+// WebhookPayload - тело запроса на callback_url
+type WebhookPayload struct {
+	Event        string                `json:"event"`
+	TaskID       string                `json:"task_id"`
+	Status       models.TaskStatus     `json:"status"`
+	FileKey      string                `json:"file_key"`
+	ProcessedKey string                `json:"processed_key,omitempty"`
+	DownloadURL  string                `json:"download_url,omitempty"`
+	Artifacts    []models.TaskArtifact `json:"artifacts,omitempty"`
+	Variants     []models.TaskVariant  `json:"variants,omitempty"`
+	Error        string                `json:"error,omitempty"`
+	Timestamp    time.Time             `json:"timestamp"`
+}
+
+type WebhookService struct {
+	cfg    *config.WebhookConfig
+	client *http.Client
+	wg     sync.WaitGroup
+}
+
+func NewWebhookService(cfg *config.WebhookConfig) *WebhookService {
+	dialer := publicDialer(cfg.AllowPrivate, ErrCallbackBlocked)
+
+	return &WebhookService{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: time.Duration(cfg.TimeoutSec) * time.Second,
+			Transport: &http.Transport{
+				// прокси из окружения обошёл бы проверку адреса
+				Proxy:                 nil,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ResponseHeaderTimeout: 15 * time.Second,
+				MaxIdleConns:          10,
+				IdleConnTimeout:       30 * time.Second,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func ValidateCallback(callback *models.Callback) error {
+	if callback == nil {
+		return nil
+	}
+
+	u, err := url.Parse(callback.URL)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf(
+			"%w: callback_url must be an absolute URL",
+			ErrInvalidCallback,
+		)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf(
+			"%w: callback_url scheme must be http or https",
+			ErrInvalidCallback,
+		)
+	}
+	return nil
+}
+
+// DeliverAsync - доставка в фоне; onAttempt вызывается после каждой попытки
+func (s *WebhookService) DeliverAsync(
+	ctx context.Context,
+	task *models.S3FileTask,
+	onAttempt func(models.CallbackAttempt),
+) {
+	if task.Callback == nil || task.Callback.URL == "" {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.deliver(context.WithoutCancel(ctx), task, onAttempt)
+	}()
+}
+
+func (s *WebhookService) deliver(
+	ctx context.Context,
+	task *models.S3FileTask,
+	onAttempt func(models.CallbackAttempt),
+) {
+	logger := logging.LoggerFromContext(ctx)
+
+	event := WebhookEventCompleted
+	if task.Status == models.TaskStatusFailed {
+		event = WebhookEventFailed
+	}
+
+	body, err := json.Marshal(WebhookPayload{
+		Event:        event,
+		TaskID:       task.ID,
+		Status:       task.Status,
+		FileKey:      task.S3FileInfo.FileKey,
+		ProcessedKey: task.ProcessedKey,
+		DownloadURL:  task.DownloadURL,
+		Artifacts:    task.Artifacts,
+		Variants:     task.Variants,
+		Error:        task.Error,
+		Timestamp:    time.Now().UTC(),
+	})
+	if err != nil {
+		logger.Error("failed to marshal webhook payload",
+			"task_id", task.ID,
+			"error", err)
+		return
+	}
+
+	for attempt := 1; attempt <= s.cfg.MaxAttempts; attempt++ {
+		started := time.Now()
+		statusCode, err := s.send(ctx, task.Callback, event, attempt, body)
+
+		record := models.CallbackAttempt{
+			Attempt:    attempt,
+			Event:      event,
+			StatusCode: statusCode,
+			DurationMs: time.Since(started).Milliseconds(),
+			At:         started,
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		onAttempt(record)
+
+		if err == nil {
+			logger.Info("webhook delivered",
+				"task_id", task.ID,
+				"event", event,
+				"attempt", attempt,
+				"status_code", statusCode)
+			return
+		}
+
+		if !isRetryableWebhookStatus(statusCode) {
+			logger.Warn("webhook rejected, not retrying",
+				"task_id", task.ID,
+				"status_code", statusCode,
+				"error", err)
+			return
+		}
+
+		if attempt == s.cfg.MaxAttempts {
+			break
+		}
+
+		delay := s.backoff(attempt)
+		logger.Warn("webhook delivery failed, retrying",
+			"task_id", task.ID,
+			"attempt", attempt,
+			"retry_in", delay,
+			"error", err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	logger.Error("webhook delivery exhausted",
+		"task_id", task.ID,
+		"event", event,
+		"attempts", s.cfg.MaxAttempts)
+}
+
+func (s *WebhookService) send(
+	ctx context.Context,
+	callback *models.Callback,
+	event string,
+	attempt int,
+	body []byte,
+) (int, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		callback.URL,
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("build webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, event)
+	req.Header.Set(webhookAttemptHeader, strconv.Itoa(attempt))
+	req.Header.Set(
+		webhookTimestampHeader,
+		strconv.FormatInt(time.Now().Unix(), 10),
+	)
+	if callback.Secret != "" {
+		req.Header.Set(
+			ut.SignatureHeader,
+			ut.SignPayload(callback.Secret, body),
+		)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf(
+			"webhook responded with status %d",
+			resp.StatusCode,
+		)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff - экспоненциальная задержка с джиттером до 20%
+func (s *WebhookService) backoff(attempt int) time.Duration {
+	delay := time.Duration(s.cfg.InitialBackoffMs) * time.Millisecond
+	maxDelay := time.Duration(s.cfg.MaxBackoffMs) * time.Millisecond
+
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxDelay)
+
+	jitter := time.Duration(rand.Int64N(int64(delay)/5 + 1))
+	return delay + jitter
+}
+
+// isRetryableWebhookStatus - сетевые ошибки (0), 408, 429 и 5xx
+func isRetryableWebhookStatus(statusCode int) bool {
+	switch {
+	case statusCode == 0:
+		return true
+	case statusCode == http.StatusRequestTimeout,
+		statusCode == http.StatusTooManyRequests:
+		return true
+	case statusCode >= 500:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown - ожидание незавершённых доставок
+func (s *WebhookService) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return nil
+	}
+}
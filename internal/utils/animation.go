@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"time"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/primitive"
+)
+
+// Форматы анимации построения рисунка
+const (
+	AnimationGIF    = "gif"    // анимированный GIF
+	AnimationFrames = "frames" // ZIP с кадрами PNG
+)
+
+// Animations - значения animation, доступные через API
+var Animations = []string{AnimationGIF, AnimationFrames}
+
+const (
+	MinFrameInterval  = 1
+	MaxFrameInterval  = MaxNumShapes
+	MinFrameDelay     = 20 // мс
+	MaxFrameDelay     = 5000
+	DefaultFrameDelay = 100
+
+	// MaxAnimationFrames - больше кадров не строится: при маленьком
+	// frame_interval интервал увеличивается
+	MaxAnimationFrames = 200
+	// defaultAnimationFrames - число кадров, если интервал не задан
+	defaultAnimationFrames = 50
+	// MaxAnimationSize - кадры крупнее превью не нужны и раздувают GIF
+	MaxAnimationSize = 512
+	// finalFrameDelay - последний кадр держится дольше перед повтором
+	finalFrameDelay = 2 * time.Second
+)
+
+// Имена файлов анимации рядом с результатом
+const (
+	ArtifactAnimation = "animation"
+	ArtifactFrames    = "frames"
+)
+
+// AnimationConfig - анимация построения рисунка фигура за фигурой
+type AnimationConfig struct {
+	Format   string // gif, frames; пусто - без анимации
+	Interval int    // фигур между кадрами; 0 - около 50 кадров
+	Delay    int    // задержка между кадрами GIF, мс
+}
+
+func NewAnimationConfig() AnimationConfig {
+	return AnimationConfig{Delay: DefaultFrameDelay}
+}
+
+// frameInterval - фигур между кадрами с учётом MaxAnimationFrames
+func (a AnimationConfig) frameInterval(shapes int) int {
+	interval := a.Interval
+	if interval <= 0 {
+		interval = ceilDiv(shapes, defaultAnimationFrames)
+	}
+	return max(interval, ceilDiv(shapes, MaxAnimationFrames), 1)
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
+
+// EncodeAnimation - кадры построения модели в размере size (не больше
+// MaxAnimationSize) в виде GIF или ZIP с PNG
+func EncodeAnimation(
+	ctx context.Context,
+	model *primitive.Model,
+	size int,
+	cfg AnimationConfig,
+) (Artifact, error) {
+	size = min(size, MaxAnimationSize)
+	interval := cfg.frameInterval(len(model.Shapes))
+
+	switch cfg.Format {
+	case AnimationGIF:
+		data, err := encodeGIFAnimation(ctx, model, size, interval, cfg.Delay)
+		if err != nil {
+			return Artifact{}, fmt.Errorf("failed to encode animation: %w", err)
+		}
+		return Artifact{
+			Name:     ArtifactAnimation,
+			Data:     data,
+			MimeType: "image/gif",
+		}, nil
+	case AnimationFrames:
+		data, err := encodeFrameArchive(ctx, model, size, interval)
+		if err != nil {
+			return Artifact{}, fmt.Errorf("failed to encode frames: %w", err)
+		}
+		return Artifact{
+			Name:     ArtifactFrames,
+			Data:     data,
+			MimeType: "application/zip",
+		}, nil
+	default:
+		return Artifact{}, fmt.Errorf("unknown animation %q", cfg.Format)
+	}
+}
+
+func encodeGIFAnimation(
+	ctx context.Context,
+	model *primitive.Model,
+	size, interval, delay int,
+) ([]byte, error) {
+	anim := &gif.GIF{}
+	err := model.Frames(ctx, size, interval,
+		func(im *image.RGBA, _ int) error {
+			// без дизеринга: шум между кадрами мерцает и плохо сжимается,
+			// а однотонным фигурам ближайшего цвета палитры хватает
+			frame := image.NewPaletted(im.Bounds(), palette.Plan9)
+			draw.Draw(frame, im.Bounds(), im, image.Point{}, draw.Src)
+			anim.Image = append(anim.Image, frame)
+			anim.Delay = append(anim.Delay, delay/10)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	anim.Delay[len(anim.Delay)-1] = int(finalFrameDelay / (10 * time.Millisecond))
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeFrameArchive - кадры frame-0000.png, frame-0001.png...; номер -
+// порядковый номер кадра, число фигур на нём пишется в комментарий записи
+func encodeFrameArchive(
+	ctx context.Context,
+	model *primitive.Model,
+	size, interval int,
+) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	index := 0
+	err := model.Frames(ctx, size, interval,
+		func(im *image.RGBA, shapes int) error {
+			// PNG уже сжат, deflate только тратит CPU
+			entry, err := zw.CreateHeader(&zip.FileHeader{
+				Name:     fmt.Sprintf("frame-%04d.png", index),
+				Comment:  fmt.Sprintf("shapes=%d", shapes),
+				Method:   zip.Store,
+				Modified: time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+			index++
+			return png.Encode(entry, im)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
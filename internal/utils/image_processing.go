@@ -1,17 +1,30 @@
 package utils
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
-	"os"
-	"os/exec"
-	"path/filepath"
+	"image"
+	"time"
 
 	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/primitive"
+)
+
+// Effect - способ обработки изображения
+type Effect string
+
+const (
+	EffectPrimitive Effect = "primitive" // аппроксимация фигурами
+	EffectPencil    Effect = "pencil"    // карандашный рисунок
 )
 
 type ImageProcessor struct {
+	Effect Effect
 	Config PrimitiveConfig
+	Pencil PencilConfig
+	Output OutputConfig
 }
 
 type PrimitiveConfig struct {
@@ -23,12 +36,16 @@ type PrimitiveConfig struct {
 	OutputSize  int    // -s: размер выходного изображения
 	Background  string // -bg: фоновый цвет (hex или "avg", "white", "black")
 	Workers     int    // -j: количество потоков (0=все ядра)
+	Seed        uint64 // зерно генератора: одинаковые Seed и Workers дают одинаковый результат
 	Verbose     bool   // -v: подробный вывод
 	VeryVerbose bool   // -vv: очень подробный вывод
 }
 
 func NewImageProcessor() *ImageProcessor {
 	return &ImageProcessor{
+		Effect: EffectPrimitive,
+		Pencil: NewPencilConfig(),
+		Output: NewOutputConfig(),
 		Config: PrimitiveConfig{
 			NumShapes:   150,
 			Mode:        1,   // треугольники
@@ -44,96 +61,125 @@ func NewImageProcessor() *ImageProcessor {
 	}
 }
 
-// CreatePencilSketch - обёртка над primitive CLI с полной конфигурацией
-func (p *ImageProcessor) CreatePencilSketch(
+// CreatePrimitiveArt - аппроксимация изображения фигурами встроенным
+// движком; вместе с результатом возвращаются запрошенные артефакты
+func (p *ImageProcessor) CreatePrimitiveArt(
 	ctx context.Context,
 	fileData []byte,
-) ([]byte, error) {
+) ([]byte, []Artifact, error) {
 	logger := logging.LoggerFromContext(ctx)
 
-	// 1. Создаём временные файлы
-	tmpDir, err := os.MkdirTemp("", "primitive-*")
+	// 1. Декодируем входное изображение
+	src, format, err := image.Decode(bytes.NewReader(fileData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+		return nil, nil, fmt.Errorf("failed to decode input image: %w", err)
 	}
-	defer os.RemoveAll(tmpDir)
-
-	// Определяем расширение входного файла (пробуем угадать формат)
-	ext := ".png" // по умолчанию
-	if len(fileData) > 8 {
-		// Простая проверка сигнатур
-		if fileData[0] == 0xFF && fileData[1] == 0xD8 {
-			ext = ".jpg"
-		} else if fileData[0] == 0x89 && fileData[1] == 0x50 {
-			ext = ".png"
-		}
-	}
-
-	inputPath := filepath.Join(tmpDir, "input"+ext)
-	outputPath := filepath.Join(tmpDir, "output.png")
 
-	// 2. Сохраняем входные данные
-	if err := os.WriteFile(inputPath, fileData, 0644); err != nil {
-		return nil, fmt.Errorf("failed to write input file: %w", err)
+	// 2. Уменьшаем до рабочего размера и выбираем фон
+	target := primitive.Resize(src, p.Config.Resize)
+	background, err := primitive.ParseBackground(p.Config.Background, target)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// 3. Формируем аргументы для primitive
-	args := []string{
-		"-i", inputPath,
-		"-o", outputPath,
-		"-n", fmt.Sprint(p.Config.NumShapes),
-		"-m", fmt.Sprint(p.Config.Mode),
-		"-a", fmt.Sprint(p.Config.Alpha),
-		"-r", fmt.Sprint(p.Config.Resize),
-		"-s", fmt.Sprint(p.Config.OutputSize),
-		"-bg", p.Config.Background,
-	}
-
-	// Добавляем опциональные параметры
-	if p.Config.Repeat > 0 {
-		args = append(args, "-rep", fmt.Sprint(p.Config.Repeat))
-	}
-
-	if p.Config.Workers > 0 {
-		args = append(args, "-j", fmt.Sprint(p.Config.Workers))
-	}
+	model := primitive.New(target, primitive.Options{
+		Shape:      primitive.ShapeType(p.Config.Mode),
+		Alpha:      p.Config.Alpha,
+		Repeat:     p.Config.Repeat,
+		Background: background,
+		Workers:    p.Config.Workers,
+		Seed:       p.Config.Seed,
+	})
 
 	if p.Config.Verbose {
-		args = append(args, "-v")
+		logger.Info("running primitive",
+			"format", format,
+			"width", model.Width,
+			"height", model.Height,
+			"shapes", p.Config.NumShapes,
+			"mode", p.Config.Mode,
+		)
 	}
 
-	if p.Config.VeryVerbose {
-		args = append(args, "-vv")
+	// 3. Добавляем фигуры по одной
+	started := time.Now()
+	for i := 0; i < p.Config.NumShapes; i++ {
+		if err := model.Step(ctx); err != nil {
+			return nil, nil, fmt.Errorf("primitive interrupted: %w", err)
+		}
+		ReportProgress(ctx, i+1, p.Config.NumShapes, model.Score)
+		if p.Config.VeryVerbose {
+			logger.Debug("primitive step",
+				"shape", i+1,
+				"score", model.Score,
+				"elapsed", time.Since(started),
+			)
+		}
 	}
 
-	// 4. Запускаем primitive
-	cmd := exec.Command("primitive", args...)
-
-	if p.Config.Verbose {
-		logger.Info("running primitive", "args", args)
+	// 4. Отрисовываем результат в выходном размере и формате
+	var result []byte
+	if p.Output.Format == FormatSVG {
+		result = []byte(model.SVG(p.Config.OutputSize))
+	} else {
+		result, err = EncodeImage(model.Render(p.Config.OutputSize), p.Output)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("primitive failed: %w", err)
+	// 5. Описание фигур отдельным файлом, если запрошено
+	var artifacts []Artifact
+	if p.Output.ShapesJSON {
+		shapes, err := json.Marshal(model.Drawing(p.Config.OutputSize))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode shapes: %w", err)
+		}
+		artifacts = append(artifacts, Artifact{
+			Name:     ArtifactShapes,
+			Data:     shapes,
+			MimeType: "application/json",
+		})
 	}
 
-	// 5. Читаем результат
-	result, err := os.ReadFile(outputPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read output: %w", err)
+	// 6. Анимация построения рисунка, если запрошена
+	if p.Output.Animation.Format != "" {
+		animation, err := EncodeAnimation(
+			ctx,
+			model,
+			p.Config.OutputSize,
+			p.Output.Animation,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		artifacts = append(artifacts, animation)
 	}
 
 	logger.Info("primitive sketch created",
 		"shapes", p.Config.NumShapes,
 		"mode", p.Config.Mode,
+		"format", p.Output.Format,
+		"score", model.Score,
+		"duration", time.Since(started),
 		"size", len(result))
 
-	return result, nil
+	return result, artifacts, nil
 }
 
 // SetStyle - быстрая настройка художественных стилей
 func (p *ImageProcessor) SetStyle(style string) {
+	p.Effect = EffectPrimitive
+
 	switch style {
+	case "pencil":
+		p.Effect = EffectPencil
+		p.Pencil = NewPencilConfig()
+	case "pencil-hatched":
+		p.Effect = EffectPencil
+		p.Pencil = NewPencilConfig()
+		p.Pencil.Hatching = true
+		p.Pencil.BlurSigma = 6
 	case "lowpoly":
 		p.Config.Mode = 1
 		p.Config.NumShapes = 150
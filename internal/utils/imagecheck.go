@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"slices"
+
+	// декодеры форматов, которые принимает API; регистрируются и для
+	// image.Decode в процессорах
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrCorruptImage      = errors.New("corrupt image")
+	// ErrImageDimensions - защита от decompression bomb: маленький файл
+	// с огромным разрешением
+	ErrImageDimensions = errors.New("image dimensions exceed limit")
+)
+
+// maxHeaderBytes - сколько байт можно прочитать в поисках заголовка
+// (у JPEG перед SOF бывают большие EXIF/ICC блоки)
+const maxHeaderBytes = 1 << 20
+
+// imageFormats - имя формата из image.DecodeConfig и его MIME-тип
+var imageFormats = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+	"bmp":  "image/bmp",
+	"tiff": "image/tiff",
+}
+
+// SupportedImageTypes - MIME-типы принимаемых форматов
+func SupportedImageTypes() []string {
+	types := make([]string, 0, len(imageFormats))
+	for _, mimeType := range imageFormats {
+		types = append(types, mimeType)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// imageSignatures - сигнатуры форматов, "?" совпадает с любым байтом
+var imageSignatures = []struct {
+	magic    string
+	mimeType string
+}{
+	{"\xff\xd8\xff", "image/jpeg"},
+	{"\x89PNG\r\n\x1a\n", "image/png"},
+	{"GIF87a", "image/gif"},
+	{"GIF89a", "image/gif"},
+	{"RIFF????WEBP", "image/webp"},
+	{"BM", "image/bmp"},
+	{"II*\x00", "image/tiff"},
+	{"MM\x00*", "image/tiff"},
+}
+
+// maxSignatureLen - длина самой длинной сигнатуры
+const maxSignatureLen = 12
+
+// SniffImageType - MIME-тип поддерживаемого формата по первым байтам
+// файла или "", если формат не распознан
+func SniffImageType(head []byte) string {
+	for _, sig := range imageSignatures {
+		if matchMagic(head, sig.magic) {
+			return sig.mimeType
+		}
+	}
+	return ""
+}
+
+func matchMagic(head []byte, magic string) bool {
+	if len(head) < len(magic) {
+		return false
+	}
+	for i := 0; i < len(magic); i++ {
+		if magic[i] != '?' && head[i] != magic[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type ImageLimits struct {
+	MaxWidth  int
+	MaxHeight int
+	MaxPixels int64
+}
+
+type ImageInfo struct {
+	Format   string
+	MimeType string
+	Width    int
+	Height   int
+}
+
+// InvalidImageError - причина отказа вместе с тем, что удалось узнать
+// о файле; errors.Is работает с ErrUnsupportedFormat, ErrCorruptImage и
+// ErrImageDimensions
+type InvalidImageError struct {
+	Reason error
+	Info   ImageInfo
+	Limits ImageLimits
+	Err    error
+}
+
+func (e *InvalidImageError) Error() string {
+	switch {
+	case errors.Is(e.Reason, ErrImageDimensions):
+		return fmt.Sprintf(
+			"%v: %dx%d, limit is %dx%d and %d pixels",
+			e.Reason,
+			e.Info.Width,
+			e.Info.Height,
+			e.Limits.MaxWidth,
+			e.Limits.MaxHeight,
+			e.Limits.MaxPixels,
+		)
+	case e.Err != nil:
+		return fmt.Sprintf("%v: %v", e.Reason, e.Err)
+	default:
+		return e.Reason.Error()
+	}
+}
+
+func (e *InvalidImageError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Reason}
+	}
+	return []error{e.Reason, e.Err}
+}
+
+// InspectImage - формат и размеры по заголовку изображения из r без
+// декодирования пикселей. Возвращённый reader отдаёт поток целиком,
+// включая прочитанный заголовок
+func InspectImage(
+	r io.Reader,
+	limits ImageLimits,
+) (*ImageInfo, io.Reader, error) {
+	var header bytes.Buffer
+	br := bufio.NewReader(
+		io.TeeReader(io.LimitReader(r, maxHeaderBytes), &header),
+	)
+
+	// сигнатура проверяется до декодера: зарегистрированные декодеры
+	// бывают менее строгими (например, любой RIFF считается WebP)
+	if head, _ := br.Peek(maxSignatureLen); SniffImageType(head) == "" {
+		return nil, nil, &InvalidImageError{Reason: ErrUnsupportedFormat}
+	}
+
+	cfg, format, err := image.DecodeConfig(br)
+	if err != nil {
+		reason := ErrCorruptImage
+		if errors.Is(err, image.ErrFormat) {
+			reason, err = ErrUnsupportedFormat, nil
+		}
+		return nil, nil, &InvalidImageError{Reason: reason, Err: err}
+	}
+
+	info := &ImageInfo{
+		Format:   format,
+		MimeType: imageFormats[format],
+		Width:    cfg.Width,
+		Height:   cfg.Height,
+	}
+	if info.MimeType == "" {
+		return nil, nil, &InvalidImageError{
+			Reason: ErrUnsupportedFormat,
+			Info:   *info,
+		}
+	}
+	if info.Width <= 0 || info.Height <= 0 {
+		return nil, nil, &InvalidImageError{
+			Reason: ErrCorruptImage,
+			Info:   *info,
+			Err:    errors.New("zero image dimensions"),
+		}
+	}
+	if exceedsLimits(info, limits) {
+		return nil, nil, &InvalidImageError{
+			Reason: ErrImageDimensions,
+			Info:   *info,
+			Limits: limits,
+		}
+	}
+
+	return info, io.MultiReader(&header, r), nil
+}
+
+func exceedsLimits(info *ImageInfo, limits ImageLimits) bool {
+	if limits.MaxWidth > 0 && info.Width > limits.MaxWidth {
+		return true
+	}
+	if limits.MaxHeight > 0 && info.Height > limits.MaxHeight {
+		return true
+	}
+	pixels := int64(info.Width) * int64(info.Height)
+	return limits.MaxPixels > 0 && pixels > limits.MaxPixels
+}
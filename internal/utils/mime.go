@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"path"
+	"strings"
+)
+
+var mimeExtensions = map[string]string{
+	"image/png":        ".png",
+	"image/jpeg":       ".jpg",
+	"image/gif":        ".gif",
+	"image/webp":       ".webp",
+	"image/svg+xml":    ".svg",
+	"image/bmp":        ".bmp",
+	"image/tiff":       ".tiff",
+	"application/json": ".json",
+	"application/zip":  ".zip",
+}
+
+// ExtensionForMime - расширение файла для MIME-типа; если тип
+// неизвестен, берётся расширение fallbackName
+func ExtensionForMime(mimeType, fallbackName string) string {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	if ext, ok := mimeExtensions[strings.TrimSpace(mimeType)]; ok {
+		return ext
+	}
+	return strings.ToLower(path.Ext(fallbackName))
+}
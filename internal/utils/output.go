@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+// OutputFormat - формат результата обработки
+type OutputFormat string
+
+const (
+	FormatPNG  OutputFormat = "png"
+	FormatJPEG OutputFormat = "jpg"
+	FormatWebP OutputFormat = "webp"
+	FormatSVG  OutputFormat = "svg" // только для эффектов из фигур
+	FormatGIF  OutputFormat = "gif"
+)
+
+// OutputFormats - значения output_format, доступные через API
+var OutputFormats = []string{"png", "jpg", "webp", "svg", "gif"}
+
+const (
+	MinQuality     = 1
+	MaxQuality     = 100
+	DefaultQuality = 90
+)
+
+var outputMimeTypes = map[OutputFormat]string{
+	FormatPNG:  "image/png",
+	FormatJPEG: "image/jpeg",
+	FormatWebP: "image/webp",
+	FormatSVG:  "image/svg+xml",
+	FormatGIF:  "image/gif",
+}
+
+// OutputConfig - кодирование результата
+type OutputConfig struct {
+	Format     OutputFormat
+	Quality    int  // качество JPEG (1-100)
+	ShapesJSON bool // описание фигур отдельным файлом shapes.json
+	Animation  AnimationConfig
+}
+
+// ArtifactShapes - имя файла с описанием фигур
+const ArtifactShapes = "shapes"
+
+// Artifact - дополнительный файл, который эффект создаёт рядом с
+// основным результатом
+type Artifact struct {
+	Name     string
+	Data     []byte
+	MimeType string
+}
+
+func NewOutputConfig() OutputConfig {
+	return OutputConfig{
+		Format:    FormatPNG,
+		Quality:   DefaultQuality,
+		Animation: NewAnimationConfig(),
+	}
+}
+
+// ParseOutputFormat - формат по значению output_format; jpeg - синоним jpg
+func ParseOutputFormat(value string) (OutputFormat, bool) {
+	format := OutputFormat(strings.ToLower(value))
+	if format == "jpeg" {
+		format = FormatJPEG
+	}
+	_, ok := outputMimeTypes[format]
+	return format, ok
+}
+
+// MimeType - Content-Type результата в этом формате
+func (f OutputFormat) MimeType() string {
+	return outputMimeTypes[f]
+}
+
+// EncodeImage - растровый результат в формате out.Format; SVG строится
+// из фигур и здесь не поддерживается
+func EncodeImage(im image.Image, out OutputConfig) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+
+	switch out.Format {
+	case FormatPNG, "":
+		err = png.Encode(&buf, im)
+	case FormatJPEG:
+		err = jpeg.Encode(&buf, im, &jpeg.Options{Quality: out.Quality})
+	case FormatWebP:
+		// кодировщик без потерь (VP8L), quality не применяется
+		err = nativewebp.Encode(&buf, im, nil)
+	case FormatGIF:
+		err = gif.Encode(&buf, toPaletted(im), nil)
+	default:
+		return nil, fmt.Errorf("cannot encode raster image as %q", out.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to encode output as %s: %w",
+			out.Format,
+			err,
+		)
+	}
+	return buf.Bytes(), nil
+}
+
+// toPaletted - 256 цветов для GIF: оттенки серого без потерь для
+// карандашного рисунка, палитра Plan 9 с дизерингом для остального
+func toPaletted(im image.Image) *image.Paletted {
+	bounds := im.Bounds()
+
+	if gray, ok := im.(*image.Gray); ok {
+		grays := make(color.Palette, 256)
+		for i := range grays {
+			grays[i] = color.Gray{Y: uint8(i)}
+		}
+		out := image.NewPaletted(bounds, grays)
+		for y := 0; y < bounds.Dy(); y++ {
+			copy(
+				out.Pix[y*out.Stride:y*out.Stride+bounds.Dx()],
+				gray.Pix[y*gray.Stride:y*gray.Stride+bounds.Dx()],
+			)
+		}
+		return out
+	}
+
+	out := image.NewPaletted(bounds, palette.Plan9)
+	draw.FloydSteinberg.Draw(out, bounds, im, bounds.Min)
+	return out
+}
@@ -0,0 +1,275 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/models"
+)
+
+// ErrInvalidParams - параметры обработки не прошли валидацию
+var ErrInvalidParams = errors.New("invalid processing parameters")
+
+const (
+	MinNumShapes  = 1
+	MaxNumShapes  = 5000
+	MinMode       = 0
+	MaxMode       = 8
+	MinAlpha      = 0
+	MaxAlpha      = 255
+	MinOutputSize = 64
+	MaxOutputSize = 4096
+	MaxVariants   = 8
+)
+
+const (
+	MinBlurSigma float64 = 0.5
+	MaxBlurSigma float64 = 50
+)
+
+// Styles - стили, доступные через API. portrait-* ведут на SetPortraitStyle.
+var Styles = []string{
+	"lowpoly",
+	"sketch",
+	"impressionism",
+	"pointillism",
+	"abstract",
+	"portrait",
+	"portrait-high",
+	"portrait-medium",
+	"portrait-low",
+	"pencil",
+	"pencil-hatched",
+}
+
+var (
+	hexColorRe    = regexp.MustCompile(`^#?[0-9a-fA-F]{6}$`)
+	variantNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+)
+
+// ValidateProcessingParams - проверка параметров задачи до постановки в очередь
+func ValidateProcessingParams(params models.ProcessingParams) error {
+	if params.Style != "" && !isKnownStyle(params.Style) {
+		return fmt.Errorf(
+			"%w: unknown style %q (available: %s)",
+			ErrInvalidParams,
+			params.Style,
+			strings.Join(Styles, ", "),
+		)
+	}
+
+	if err := checkRange(
+		"num_shapes", params.NumShapes, MinNumShapes, MaxNumShapes,
+	); err != nil {
+		return err
+	}
+	if err := checkRange("mode", params.Mode, MinMode, MaxMode); err != nil {
+		return err
+	}
+	if err := checkRange("alpha", params.Alpha, MinAlpha, MaxAlpha); err != nil {
+		return err
+	}
+	if err := checkRange(
+		"output_size", params.OutputSize, MinOutputSize, MaxOutputSize,
+	); err != nil {
+		return err
+	}
+
+	// в такой форме сравнение отвергает и NaN из формы (blur_sigma=NaN)
+	if params.BlurSigma != nil &&
+		!(*params.BlurSigma >= MinBlurSigma && *params.BlurSigma <= MaxBlurSigma) {
+		return fmt.Errorf(
+			"%w: blur_sigma must be between %g and %g, got %g",
+			ErrInvalidParams,
+			MinBlurSigma, MaxBlurSigma, *params.BlurSigma,
+		)
+	}
+
+	if params.OutputFormat != "" {
+		if _, ok := ParseOutputFormat(params.OutputFormat); !ok {
+			return fmt.Errorf(
+				"%w: unknown output_format %q (available: %s)",
+				ErrInvalidParams,
+				params.OutputFormat,
+				strings.Join(OutputFormats, ", "),
+			)
+		}
+	}
+	if err := checkRange(
+		"quality", params.Quality, MinQuality, MaxQuality,
+	); err != nil {
+		return err
+	}
+
+	if params.Animation != "" && !slices.Contains(Animations, params.Animation) {
+		return fmt.Errorf(
+			"%w: unknown animation %q (available: %s)",
+			ErrInvalidParams,
+			params.Animation,
+			strings.Join(Animations, ", "),
+		)
+	}
+	if err := checkRange(
+		"frame_interval",
+		params.FrameInterval,
+		MinFrameInterval,
+		MaxFrameInterval,
+	); err != nil {
+		return err
+	}
+	if err := checkRange(
+		"frame_delay", params.FrameDelay, MinFrameDelay, MaxFrameDelay,
+	); err != nil {
+		return err
+	}
+
+	if params.Background != "" && !isValidBackground(params.Background) {
+		return fmt.Errorf(
+			"%w: background must be avg, white, black or hex color, got %q",
+			ErrInvalidParams,
+			params.Background,
+		)
+	}
+
+	return nil
+}
+
+// ValidateVariants - число вариантов и их имена (имя входит в ключ S3).
+// Параметры каждого варианта проверяются отдельно после слияния с основными
+func ValidateVariants(variants []models.VariantSpec) error {
+	if len(variants) > MaxVariants {
+		return fmt.Errorf(
+			"%w: at most %d variants allowed, got %d",
+			ErrInvalidParams,
+			MaxVariants,
+			len(variants),
+		)
+	}
+
+	seen := make(map[string]bool, len(variants))
+	for _, v := range variants {
+		if v.Name == "" {
+			continue
+		}
+		if !variantNameRe.MatchString(v.Name) {
+			return fmt.Errorf(
+				"%w: variant name %q must be 1-64 letters, digits, _ or -",
+				ErrInvalidParams,
+				v.Name,
+			)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf(
+				"%w: duplicate variant name %q",
+				ErrInvalidParams,
+				v.Name,
+			)
+		}
+		seen[v.Name] = true
+	}
+	return nil
+}
+
+// NewImageProcessorFromParams - отдельный процессор на задачу:
+// сначала применяется стиль, затем явные переопределения клиента
+func NewImageProcessorFromParams(
+	params models.ProcessingParams,
+) (*ImageProcessor, error) {
+	if err := ValidateProcessingParams(params); err != nil {
+		return nil, err
+	}
+
+	p := NewImageProcessor()
+
+	switch {
+	case strings.HasPrefix(params.Style, "portrait-"):
+		p.SetPortraitStyle(strings.TrimPrefix(params.Style, "portrait-"))
+	case params.Style != "":
+		p.SetStyle(params.Style)
+	}
+
+	if params.NumShapes != nil {
+		p.Config.NumShapes = *params.NumShapes
+	}
+	if params.Mode != nil {
+		p.Config.Mode = *params.Mode
+	}
+	if params.Alpha != nil {
+		p.Config.Alpha = *params.Alpha
+	}
+	if params.Background != "" {
+		p.Config.Background = strings.TrimPrefix(params.Background, "#")
+	}
+	if params.OutputSize != nil {
+		p.Config.OutputSize = *params.OutputSize
+	}
+	if params.BlurSigma != nil {
+		p.Pencil.BlurSigma = *params.BlurSigma
+	}
+	if params.PaperTexture != nil {
+		p.Pencil.PaperTexture = *params.PaperTexture
+	}
+	if params.Hatching != nil {
+		p.Pencil.Hatching = *params.Hatching
+	}
+	if params.OutputFormat != "" {
+		p.Output.Format, _ = ParseOutputFormat(params.OutputFormat)
+	}
+	if params.Quality != nil {
+		p.Output.Quality = *params.Quality
+	}
+	if params.ShapesJSON != nil {
+		p.Output.ShapesJSON = *params.ShapesJSON
+	}
+	p.Output.Animation.Format = params.Animation
+	if params.FrameInterval != nil {
+		p.Output.Animation.Interval = *params.FrameInterval
+	}
+	if params.FrameDelay != nil {
+		p.Output.Animation.Delay = *params.FrameDelay
+	}
+
+	return p, nil
+}
+
+// EffectForStyle - эффект, к которому относится стиль
+func EffectForStyle(style string) Effect {
+	if strings.HasPrefix(style, "pencil") {
+		return EffectPencil
+	}
+	return EffectPrimitive
+}
+
+func isKnownStyle(style string) bool {
+	for _, s := range Styles {
+		if s == style {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidBackground(bg string) bool {
+	switch bg {
+	case "avg", "white", "black":
+		return true
+	}
+	return hexColorRe.MatchString(bg)
+}
+
+func checkRange(name string, value *int, lo, hi int) error {
+	if value == nil {
+		return nil
+	}
+	if *value < lo || *value > hi {
+		return fmt.Errorf(
+			"%w: %s must be between %d and %d, got %d",
+			ErrInvalidParams,
+			name, lo, hi, *value,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/BagRoman01/image-sketch-processor/internal/logging"
+	"github.com/BagRoman01/image-sketch-processor/internal/primitive"
+)
+
+type PencilConfig struct {
+	BlurSigma     float64 // сила размытия негатива: больше - толще линии
+	PaperTexture  bool    // зерно бумаги поверх рисунка
+	PaperStrength float64 // интенсивность зерна (0-1)
+	Hatching      bool    // штриховка тёмных областей
+	HatchSpacing  int     // шаг между штрихами в пикселях
+	Seed          uint64  // зерно для текстуры бумаги
+}
+
+func NewPencilConfig() PencilConfig {
+	return PencilConfig{
+		BlurSigma:     8,
+		PaperTexture:  true,
+		PaperStrength: 0.08,
+		Hatching:      false,
+		HatchSpacing:  6,
+	}
+}
+
+// CreatePencilSketch - карандашный рисунок: оттенки серого, негатив,
+// размытие по Гауссу и смешивание color dodge
+func (p *ImageProcessor) CreatePencilSketch(
+	ctx context.Context,
+	fileData []byte,
+) ([]byte, error) {
+	logger := logging.LoggerFromContext(ctx)
+	started := time.Now()
+
+	src, _, err := image.Decode(bytes.NewReader(fileData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode input image: %w", err)
+	}
+
+	const stages = 4
+	ReportProgress(ctx, 0, stages, 0)
+
+	gray := toGray(primitive.Resize(src, p.Config.OutputSize))
+	w, h := gray.Rect.Dx(), gray.Rect.Dy()
+
+	inverted := make([]float64, w*h)
+	for i, v := range gray.Pix {
+		inverted[i] = 255 - float64(v)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ReportProgress(ctx, 1, stages, 0)
+	blurred := gaussianBlur(inverted, w, h, p.Pencil.BlurSigma)
+	ReportProgress(ctx, 2, stages, 0)
+
+	sketch := make([]float64, w*h)
+	for i, v := range gray.Pix {
+		sketch[i] = colorDodge(float64(v), blurred[i])
+	}
+
+	if p.Pencil.Hatching {
+		applyHatching(sketch, gray.Pix, w, h, p.Pencil.HatchSpacing)
+	}
+
+	if p.Pencil.PaperTexture {
+		applyPaperTexture(
+			sketch, w, h,
+			p.Pencil.PaperStrength,
+			p.Pencil.Seed,
+		)
+	}
+
+	ReportProgress(ctx, 3, stages, 0)
+
+	out := image.NewGray(image.Rect(0, 0, w, h))
+	for i, v := range sketch {
+		out.Pix[i] = uint8(clampFloat(math.Round(v), 0, 255))
+	}
+
+	result, err := EncodeImage(out, p.Output)
+	if err != nil {
+		return nil, err
+	}
+	ReportProgress(ctx, stages, stages, 0)
+
+	logger.Info("pencil sketch created",
+		"width", w,
+		"height", h,
+		"blur_sigma", p.Pencil.BlurSigma,
+		"hatching", p.Pencil.Hatching,
+		"format", p.Output.Format,
+		"duration", time.Since(started),
+		"size", len(result))
+
+	return result, nil
+}
+
+func toGray(im image.Image) *image.Gray {
+	b := im.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			gray.Set(x, y, im.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return gray
+}
+
+// gaussianBlur - раздельное размытие: сначала по строкам, затем по столбцам
+func gaussianBlur(src []float64, w, h int, sigma float64) []float64 {
+	if sigma <= 0 {
+		return src
+	}
+
+	radius := int(math.Ceil(sigma * 3))
+	kernel := make([]float64, 2*radius+1)
+	var sum float64
+	for i := range kernel {
+		d := float64(i - radius)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+
+	tmp := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		row := y * w
+		for x := 0; x < w; x++ {
+			var v float64
+			for k, kv := range kernel {
+				sx := clampIndex(x+k-radius, w)
+				v += src[row+sx] * kv
+			}
+			tmp[row+x] = v
+		}
+	}
+
+	dst := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var v float64
+			for k, kv := range kernel {
+				sy := clampIndex(y+k-radius, h)
+				v += tmp[sy*w+x] * kv
+			}
+			dst[y*w+x] = v
+		}
+	}
+
+	return dst
+}
+
+// colorDodge - base / (1 - blend), значения в диапазоне 0-255
+func colorDodge(base, blend float64) float64 {
+	if blend >= 255 {
+		return 255
+	}
+	return math.Min(255, base*255/(255-blend))
+}
+
+// applyHatching - диагональные штрихи, тем плотнее, чем темнее исходник:
+// один слой для средних тонов, перекрёстный для тёмных
+func applyHatching(sketch []float64, gray []uint8, w, h, spacing int) {
+	if spacing < 2 {
+		spacing = 2
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			tone := float64(gray[i]) / 255
+
+			var darken float64
+			if tone < 0.6 && (x+y)%spacing == 0 {
+				darken += (0.6 - tone) * 0.8
+			}
+			if tone < 0.35 && (x-y+h*spacing)%spacing == 0 {
+				darken += (0.35 - tone) * 1.2
+			}
+			if darken > 0 {
+				sketch[i] *= 1 - math.Min(darken, 0.8)
+			}
+		}
+	}
+}
+
+// applyPaperTexture - мелкое зерно бумаги: шум, слегка размытый и
+// наложенный умножением
+func applyPaperTexture(
+	sketch []float64,
+	w, h int,
+	strength float64,
+	seed uint64,
+) {
+	rnd := rand.New(rand.NewPCG(seed, 0x9e3779b97f4a7c15))
+	noise := make([]float64, w*h)
+	for i := range noise {
+		noise[i] = rnd.Float64()
+	}
+	noise = gaussianBlur(noise, w, h, 0.7)
+
+	for i := range sketch {
+		sketch[i] *= 1 - strength*noise[i]
+	}
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
+
+func clampFloat(x, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, x))
+}
@@ -0,0 +1,21 @@
+package utils
+
+import "context"
+
+// ProgressFunc - колбэк прогресса: выполнено done шагов из total,
+// score - текущее отклонение от оригинала (0, если не применимо)
+type ProgressFunc func(done, total int, score float64)
+
+type progressKey struct{}
+
+// WithProgress - контекст, в который обработчики сообщают о прогрессе
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress - сообщить о прогрессе, если кто-то его слушает
+func ReportProgress(ctx context.Context, done, total int, score float64) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && fn != nil {
+		fn(done, total, score)
+	}
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// SignatureHeader - заголовок с подписью тела вебхука
+	SignatureHeader = "X-Webhook-Signature"
+	signaturePrefix = "sha256="
+)
+
+// SignPayload - HMAC-SHA256 тела в формате "sha256=<hex>"
+func SignPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature - сравнение подписи за постоянное время
+func VerifySignature(secret string, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	expected := SignPayload(secret, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}